@@ -0,0 +1,82 @@
+package cbostat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+// ImportOptions configure Apply.
+type ImportOptions struct {
+	// SkipDDL applies the statistics to an existing schema, without the
+	// extensions and the DDL of the bundle.
+	SkipDDL bool
+	// SkipExtendedStatistics leaves out the extended statistics, and
+	// Relpages also applies the relpages estimated from DocDB sizes.
+	SkipExtendedStatistics bool
+	Relpages               bool
+	// Verify checks the statistics from a new session, opened with the
+	// config of the connection, and fails with ErrStatisticsNotVisible when
+	// they are not seen.
+	Verify bool
+	// BatchSize is the number of statements applied per transaction, and
+	// MaxRetries bounds the retries of a batch failing with a transient
+	// error. Zero selects the defaults and a negative MaxRetries disables
+	// retries.
+	BatchSize  int
+	MaxRetries int
+
+	// Log receives the warnings of the scripts and, with Verbose, progress
+	// messages; nil discards them.
+	Log     io.Writer
+	Verbose bool
+}
+
+// Apply applies bundle to the database db is connected to. Schema
+// statements run outside of transactions, as with psql, and statistics in
+// transactions of BatchSize statements, so a failed Apply leaves what it
+// already applied. The scripts of the bundle set session settings such as
+// search_path on the connection.
+func Apply(ctx context.Context, db DB, bundle *Bundle, opts ImportOptions) error {
+	conn, release, err := acquire(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	dir, err := os.MkdirTemp("", "cbostat-")
+	if err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := bundle.WriteDir(dir); err != nil {
+		return err
+	}
+
+	log := opts.Log
+	if log == nil {
+		log = io.Discard
+	}
+	maxRetries := opts.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = dump.DefaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	retrier := dump.NewRetrier(maxRetries, opts.Verbose)
+	retrier.Log = log
+	return dump.ApplyDump(ctx, conn, dir, dump.ImportOptions{
+		SkipDDL:            opts.SkipDDL,
+		ExtendedStatistics: !opts.SkipExtendedStatistics,
+		Relpages:           opts.Relpages,
+		Verify:             opts.Verify,
+		BatchSize:          opts.BatchSize,
+		Retrier:            retrier,
+		Verbose:            opts.Verbose,
+		Log:                log,
+	})
+}
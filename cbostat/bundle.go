@@ -0,0 +1,155 @@
+package cbostat
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+// Bundle is a captured database: the files cbo_stat_dump writes to its
+// output directory, such as statistics.json and ddl.sql, by slash separated
+// path.
+type Bundle struct {
+	Files map[string][]byte
+}
+
+// names returns the file names of b, sorted.
+func (b *Bundle) names() []string {
+	names := make([]string, 0, len(b.Files))
+	for name := range b.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// QueryPlan returns the plan captured for Options.Query, one line per
+// element, or nil without a query.
+func (b *Bundle) QueryPlan() []string {
+	plan, ok := b.Files[dump.QueryPlanFile]
+	if !ok {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(string(plan), "\n"), "\n")
+}
+
+// WriteDir writes the files of b to dir, like a cbo_stat_dump output
+// directory, creating it when needed.
+func (b *Bundle) WriteDir(dir string) error {
+	for _, name := range b.names() {
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			return fmt.Errorf("invalid bundle file name %q", name)
+		}
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+		if err := os.WriteFile(p, b.Files[name], 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WriteTo writes b to w as a tar archive, which ReadBundle reads back and
+// tar extracts into a cbo_stat_dump output directory.
+func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	tw := tar.NewWriter(cw)
+	for _, name := range b.names() {
+		data := b.Files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			return cw.n, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return cw.n, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return cw.n, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return cw.n, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadBundle reads a bundle written by Bundle.WriteTo.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	b := &Bundle{Files: make(map[string][]byte)}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, fmt.Errorf("invalid bundle entry %q", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from bundle: %w", name, err)
+		}
+		b.Files[name] = data
+	}
+}
+
+// ReadBundleDir reads the bundle of a cbo_stat_dump output directory. It
+// fails on an incomplete dump.
+func ReadBundleDir(dir string) (*Bundle, error) {
+	if reason, err := os.ReadFile(filepath.Join(dir, dump.IncompleteFile)); err == nil {
+		line, _, _ := strings.Cut(string(reason), "\n")
+		return nil, fmt.Errorf("%s holds an incomplete dump: %s", dir, line)
+	}
+
+	b := &Bundle{Files: make(map[string][]byte)}
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return fmt.Errorf("unexpected file %s in dump directory", p)
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		b.Files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump directory: %w", err)
+	}
+	if len(b.Files) == 0 {
+		return nil, errors.New("empty dump directory")
+	}
+	return b, nil
+}
@@ -0,0 +1,101 @@
+package cbostat
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{Files: map[string][]byte{
+		dump.VersionFile:             []byte("PostgreSQL 15.2\n"),
+		dump.QueryPlanFile:           []byte("Seq Scan on t\n  Filter: (a = 1)\n"),
+		"gflags/tserver-1.json":      []byte("{}"),
+		dump.StatisticsJSONFile:      []byte(`{"version": "1.0.0", "pg_class": [], "pg_statistic": []}`),
+		dump.ImportStatisticsSQLFile: []byte(""),
+	}}
+}
+
+func TestBundleArchiveRoundTrip(t *testing.T) {
+	b := testBundle()
+	var buf bytes.Buffer
+	n, err := b.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d bytes, wrote %d", n, buf.Len())
+	}
+	got, err := ReadBundle(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Files, b.Files) {
+		t.Errorf("expected %v, got %v", b.Files, got.Files)
+	}
+	if plan := got.QueryPlan(); !reflect.DeepEqual(plan, []string{"Seq Scan on t", "  Filter: (a = 1)"}) {
+		t.Errorf("unexpected plan %q", plan)
+	}
+}
+
+func TestBundleDirRoundTrip(t *testing.T) {
+	b := testBundle()
+	dir := t.TempDir()
+	if err := b.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gflags", "tserver-1.json")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadBundleDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Files, b.Files) {
+		t.Errorf("expected %v, got %v", b.Files, got.Files)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, dump.IncompleteFile), []byte("dump interrupted: context canceled\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadBundleDir(dir); err == nil || !strings.Contains(err.Error(), "dump interrupted") {
+		t.Errorf("expected an incomplete dump error, got %v", err)
+	}
+}
+
+func TestBundleRejectsEscapingNames(t *testing.T) {
+	b := &Bundle{Files: map[string][]byte{"../evil.sql": []byte("DROP TABLE t;")}}
+	if err := b.WriteDir(t.TempDir()); err == nil {
+		t.Error("expected WriteDir to reject a name outside the directory")
+	}
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadBundle(&buf); err == nil {
+		t.Error("expected ReadBundle to reject a name outside the directory")
+	}
+}
+
+func TestUnsupportedDB(t *testing.T) {
+	var db DB = fakeDB{}
+	if _, err := Capture(context.Background(), db, Options{}); err == nil || !strings.Contains(err.Error(), "unsupported connection type") {
+		t.Errorf("expected an unsupported connection error, got %v", err)
+	}
+	if err := Apply(context.Background(), db, testBundle(), ImportOptions{}); err == nil {
+		t.Error("expected an unsupported connection error")
+	}
+}
+
+type fakeDB struct{}
+
+func (fakeDB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
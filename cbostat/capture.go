@@ -0,0 +1,191 @@
+package cbostat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+// Options configure Capture. The zero value captures every relation of a
+// PostgreSQL database with the defaults of cbo_stat_dump.
+type Options struct {
+	// Query, when set, restricts the capture to the relations it reads and
+	// its plan is captured. Unless AllowNonSelect is set it must be a
+	// single SELECT or WITH statement; it is only EXPLAINed.
+	Query          string
+	AllowNonSelect bool
+
+	// YBMode also captures the YugabyteDB table properties, topology and
+	// gflags.
+	YBMode                   bool
+	EnableBaseScansCostModel bool
+	// AppRole is the role whose database and role level settings are
+	// captured; empty is the connecting user.
+	AppRole string
+
+	// SkipDDL leaves the schema out of the bundle. Capturing it runs
+	// pg_dump, which must then be in PATH, with the settings and password
+	// of the connection.
+	SkipDDL bool
+	// DDLTarget converts the schema to another dialect, "pg" or "yb", with
+	// DDLKeyPolicy ("asc", "hash" or "keep") sharding the key columns
+	// without an ordering; empty keeps the dialect of the source.
+	DDLTarget    string
+	DDLKeyPolicy string
+
+	// EstimateRelpages also captures, in YB mode, relpages estimated from
+	// the DocDB size of each relation.
+	EstimateRelpages bool
+
+	// Jobs bounds the exporters running concurrently, on a *pgxpool.Pool
+	// only, and RelationBatchSize the relations queried at once. Zero
+	// selects the defaults.
+	Jobs              int
+	RelationBatchSize int
+	// MaxRetries bounds the retries of a query failing with a transient
+	// error. Zero selects the default and a negative value disables them.
+	MaxRetries int
+	// StatementTimeout and LockTimeout bound the statements of the capture.
+	// Zero selects the defaults and a negative value keeps the server
+	// settings.
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+	// PhaseTimeout bounds each phase of the capture, and PhaseTimeouts the
+	// phases it names (see Phases); zero means no limit.
+	PhaseTimeout  time.Duration
+	PhaseTimeouts map[string]time.Duration
+
+	// Web gives access to the YugabyteDB web endpoints the gflags are read
+	// from.
+	Web WebOptions
+
+	// Log receives warnings and, with Verbose, progress messages; nil
+	// discards them.
+	Log     io.Writer
+	Verbose bool
+}
+
+// WebOptions give access to the YugabyteDB web endpoints. Endpoints are
+// host:port or URLs; when empty they are discovered via yb_servers() on the
+// given ports, 7000 and 9000 when zero.
+type WebOptions struct {
+	MasterEndpoints    []string
+	TServerEndpoints   []string
+	MasterPort         int
+	TServerPort        int
+	TLS                bool
+	CACert             string
+	InsecureSkipVerify bool
+	User               string
+	Password           string
+	// Timeout bounds each request; zero is 2 seconds.
+	Timeout time.Duration
+	// AllGFlags also captures the gflags left at their default value.
+	AllGFlags bool
+}
+
+// config returns the dump config of opts writing to dir.
+func (opts Options) config(dir string) dump.Config {
+	orDefault := func(v, def int) int {
+		switch {
+		case v == 0:
+			return def
+		case v < 0:
+			return 0
+		}
+		return v
+	}
+	timeout := func(v, def time.Duration) time.Duration {
+		switch {
+		case v == 0:
+			return def
+		case v < 0:
+			return 0
+		}
+		return v
+	}
+	log := opts.Log
+	if log == nil {
+		log = io.Discard
+	}
+	keyPolicy := opts.DDLKeyPolicy
+	if keyPolicy == "" {
+		keyPolicy = dump.KeyPolicyASC
+	}
+	return dump.Config{
+		OutputDir:                dir,
+		Query:                    opts.Query,
+		AllowNonSelect:           opts.AllowNonSelect,
+		YBMode:                   opts.YBMode,
+		EnableBaseScansCostModel: opts.EnableBaseScansCostModel,
+		AppRole:                  opts.AppRole,
+		SkipDDL:                  opts.SkipDDL,
+		DDLTarget:                opts.DDLTarget,
+		DDLKeyPolicy:             keyPolicy,
+		EstimateRelpages:         opts.EstimateRelpages,
+		Jobs:                     orDefault(opts.Jobs, dump.DefaultJobs),
+		RelationBatchSize:        orDefault(opts.RelationBatchSize, dump.DefaultRelationBatchSize),
+		MaxRetries:               orDefault(opts.MaxRetries, dump.DefaultMaxRetries),
+		StatementTimeout:         timeout(opts.StatementTimeout, dump.DefaultStatementTimeout),
+		LockTimeout:              timeout(opts.LockTimeout, dump.DefaultLockTimeout),
+		PhaseTimeout:             opts.PhaseTimeout,
+		PhaseTimeouts:            opts.PhaseTimeouts,
+		MasterWebEndpoints:       opts.Web.MasterEndpoints,
+		TServerWebEndpoints:      opts.Web.TServerEndpoints,
+		MasterWebPort:            orDefault(opts.Web.MasterPort, dump.DefaultMasterWebPort),
+		TServerWebPort:           orDefault(opts.Web.TServerPort, dump.DefaultTServerWebPort),
+		WebTLS:                   opts.Web.TLS,
+		WebCACert:                opts.Web.CACert,
+		WebInsecureSkipVerify:    opts.Web.InsecureSkipVerify,
+		WebUser:                  opts.Web.User,
+		WebPassword:              opts.Web.Password,
+		WebTimeout:               timeout(opts.Web.Timeout, dump.DefaultWebTimeout),
+		GFlagsAll:                opts.Web.AllGFlags,
+		Verbose:                  opts.Verbose,
+		Log:                      log,
+	}
+}
+
+// Capture captures the database db is connected to. All catalog reads see a
+// single snapshot, in read only transactions that are rolled back, so the
+// session settings of db are left as they were. On a *pgxpool.Pool up to
+// Options.Jobs exporters run concurrently; otherwise everything runs in
+// order on the connection.
+func Capture(ctx context.Context, db DB, opts Options) (*Bundle, error) {
+	if opts.DDLTarget != "" && opts.DDLTarget != dump.DDLDialectPG && opts.DDLTarget != dump.DDLDialectYB {
+		return nil, fmt.Errorf("invalid DDLTarget %q, expected pg or yb", opts.DDLTarget)
+	}
+	dir, err := os.MkdirTemp("", "cbostat-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := opts.config(dir)
+	var dumper *dump.Dumper
+	if pool, ok := db.(*pgxpool.Pool); ok {
+		poolConfig := pool.Config()
+		cfg.UseConnConfig(poolConfig.ConnConfig)
+		// Each exporter holds a connection, and may take another one per
+		// relation batch, beside the one of the snapshot.
+		cfg.Jobs = max(1, min(cfg.Jobs, int(poolConfig.MaxConns-1)/2))
+		dumper = dump.NewDumper(pool, cfg)
+	} else {
+		conn, release, err := acquire(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		cfg.UseConnConfig(conn.Config())
+		dumper = dump.NewConnDumper(conn, cfg)
+	}
+	if err := dumper.Dump(ctx); err != nil {
+		return nil, err
+	}
+	return ReadBundleDir(dir)
+}
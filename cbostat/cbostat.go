@@ -0,0 +1,51 @@
+// Package cbostat captures the optimizer statistics, schema and planner
+// settings of a PostgreSQL or YugabyteDB database into a Bundle, and applies
+// a Bundle to another database to reproduce the plans of the source. It is
+// the library behind the cbo_stat_dump command, for services capturing
+// statistics themselves, for instance when they detect a slow query.
+//
+// Capture and Apply run on a connection owned by the caller. They never
+// print to standard output nor exit the process; progress messages and
+// warnings go to the Log of their options.
+package cbostat
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+// DB is a database connection owned by the caller: a *pgx.Conn, a
+// *pgxpool.Conn or a *pgxpool.Pool. Capture and Apply use it without
+// closing it.
+type DB interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// ErrStatisticsNotVisible is returned by Apply with Verify when the applied
+// statistics are not seen by a new session.
+var ErrStatisticsNotVisible = dump.ErrStatisticsNotVisible
+
+// Phases are the phases of a capture that Options.PhaseTimeouts can bound.
+var Phases = append([]string(nil), dump.Phases...)
+
+// acquire returns a connection of db and the function releasing it.
+func acquire(ctx context.Context, db DB) (*pgx.Conn, func(), error) {
+	switch db := db.(type) {
+	case *pgx.Conn:
+		return db, func() {}, nil
+	case *pgxpool.Conn:
+		return db.Conn(), func() {}, nil
+	case *pgxpool.Pool:
+		conn, err := db.Acquire(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+		}
+		return conn.Conn(), conn.Release, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported connection type %T, expected *pgx.Conn, *pgxpool.Conn or *pgxpool.Pool", db)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+var dumpCommand = &command{
+	name:    "dump",
+	args:    "",
+	summary: "Capture the statistics, schema and planner settings of a database into an output directory.",
+	setup:   setupDump,
+}
+
+func setupDump(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+	config := dump.Config{}
+	conn := addConnFlags(fs, &config)
+
+	fs.StringVar(&config.OutputDir, "o", "", "Output directory")
+	fs.StringVar(&config.QueryFile, "q", "", "Query file path")
+	fs.StringVar(&config.AppRole, "app_role", "", "Application role whose database and role level settings are exported (default: connecting user)")
+	fs.BoolVar(&config.YBMode, "yb_mode", false, "Use YugabyteDB mode")
+	fs.BoolVar(&config.EnableBaseScansCostModel, "enable_base_scans_cost_model", false, "Enable base scans cost model")
+	fs.BoolVar(&config.Verbose, "v", false, "Verbose output")
+	fs.IntVar(&config.MaxRetries, "max_retries", dump.DefaultMaxRetries, "Retries of a catalog query failing with a transient error")
+	fs.DurationVar(&config.StatementTimeout, "statement_timeout", dump.DefaultStatementTimeout, "statement_timeout of the capture transactions, 0 keeps the server setting")
+	fs.DurationVar(&config.LockTimeout, "lock_timeout", dump.DefaultLockTimeout, "lock_timeout of the capture transactions and pg_dump, 0 keeps the server setting")
+	fs.BoolVar(&config.AllowNonSelect, "allow_non_select", false, "Accept query files that are not a single SELECT or WITH statement")
+	fs.Func("phase_timeout", "Timeout of each dump phase, as a duration or a comma separated list such as 10m,statistics=1h (phases: "+strings.Join(dump.Phases, ", ")+")", config.SetPhaseTimeouts)
+	fs.IntVar(&config.Jobs, "j", dump.DefaultJobs, "Number of exporters run concurrently")
+	fs.IntVar(&config.RelationBatchSize, "relation_batch_size", dump.DefaultRelationBatchSize, "Number of relations queried per batch")
+
+	fs.Func("master_web", "Comma separated YB master web endpoints (host:port or URL), discovered when empty", func(s string) error {
+		config.MasterWebEndpoints = splitList(s)
+		return nil
+	})
+	fs.Func("tserver_web", "Comma separated YB tserver web endpoints (host:port or URL), discovered via yb_servers() when empty", func(s string) error {
+		config.TServerWebEndpoints = splitList(s)
+		return nil
+	})
+	fs.IntVar(&config.MasterWebPort, "master_web_port", dump.DefaultMasterWebPort, "YB master web port used for discovered nodes")
+	fs.IntVar(&config.TServerWebPort, "tserver_web_port", dump.DefaultTServerWebPort, "YB tserver web port used for discovered nodes")
+	fs.BoolVar(&config.WebTLS, "web_tls", false, "Use https for YB web endpoints")
+	fs.StringVar(&config.WebCACert, "web_ca_cert", "", "CA certificate file for YB web endpoints")
+	fs.BoolVar(&config.WebInsecureSkipVerify, "web_insecure", false, "Skip TLS certificate verification for YB web endpoints")
+	fs.StringVar(&config.WebUser, "web_user", "", "User for basic authentication on YB web endpoints")
+	// Like the database password, the web password is never taken on the
+	// command line.
+	webPassword := dump.PasswordOptions{Label: "Web password"}
+	fs.BoolVar(&webPassword.Prompt, "web_W", false, "Prompt for the password for basic authentication on YB web endpoints")
+	fs.StringVar(&webPassword.File, "web_password_file", "", "Read the YB web password from the first line of a file")
+	fs.StringVar(&webPassword.Env, "web_password_env", "", "Read the YB web password from the named environment variable")
+	fs.BoolVar(&webPassword.Stdin, "web_password_stdin", false, "Read the YB web password from the first line of standard input")
+	fs.DurationVar(&config.WebTimeout, "web_timeout", dump.DefaultWebTimeout, "Timeout for YB web requests")
+	fs.StringVar(&config.DDLTarget, "ddl_target", "", "Convert ddl.sql to another dialect: pg or yb (default: dialect of the source)")
+	fs.StringVar(&config.DDLKeyPolicy, "ddl_key_policy", dump.KeyPolicyASC, "Sharding of key columns without an ordering when converting to yb: asc, hash or keep")
+	fs.BoolVar(&config.StatisticsNDJSON, "ndjson", false, "Write statistics.ndjson, one row per line, instead of statistics.json")
+	fs.BoolVar(&config.EstimateRelpages, "estimate_relpages", false, "Export relpages estimated from DocDB tablet sizes into relpages.json and import_relpages.sql (YB mode)")
+	fs.BoolVar(&config.GFlagsAll, "gflags_all", false, "Also export gflags left at their default value")
+
+	return func(ctx context.Context, args []string) (any, error) {
+		if len(args) > 0 {
+			// -W used to take the password, which now stops flag parsing.
+			// The argument is not echoed, since it may be that password.
+			return nil, usageErrorf("unexpected positional argument. -W no longer takes the password, use -W alone to be prompted, -password_file, -password_env or -password_stdin")
+		}
+		if err := conn.resolve(true); err != nil {
+			return nil, err
+		}
+		if webPassword.Stdin && conn.password.Stdin {
+			return nil, usageErrorf("-password_stdin and -web_password_stdin cannot be used together")
+		}
+		var err error
+		if config.WebPassword, err = dump.ReadPassword(webPassword); err != nil {
+			return nil, fmt.Errorf("failed to read the web password: %w", err)
+		}
+		if config.OutputDir == "" {
+			return nil, usageErrorf("output directory is required")
+		}
+		if err := validateDDLTarget(config.DDLTarget, config.DDLKeyPolicy); err != nil {
+			return nil, err
+		}
+
+		if err := dump.Run(ctx, config); err != nil {
+			return nil, err
+		}
+		return dump.InspectDump(config.OutputDir)
+	}
+}
+
+// validateDDLTarget checks the -ddl_target and -ddl_key_policy flags.
+func validateDDLTarget(target, keyPolicy string) error {
+	if target != "" && target != dump.DDLDialectPG && target != dump.DDLDialectYB {
+		return usageErrorf("invalid -ddl_target %q, expected pg or yb", target)
+	}
+	switch keyPolicy {
+	case dump.KeyPolicyASC, dump.KeyPolicyHash, dump.KeyPolicyKeep:
+	default:
+		return usageErrorf("invalid -ddl_key_policy %q, expected asc, hash or keep", keyPolicy)
+	}
+	return nil
+}
+
+// printSummary prints the summary of a dump directory.
+func printSummary(summary *dump.DumpSummary) {
+	fmt.Printf("Dump:               %s\n", summary.Dir)
+	if summary.Incomplete != "" {
+		fmt.Printf("Status:             INCOMPLETE (%s)\n", summary.Incomplete)
+	} else {
+		fmt.Printf("Status:             complete\n")
+	}
+	if summary.ServerVersion != "" {
+		fmt.Printf("Server version:     %s\n", summary.ServerVersion)
+	}
+	if summary.Snapshot != nil {
+		fmt.Printf("Captured at:        %s (%s)\n", summary.Snapshot.CapturedAt.Format(time.RFC3339), summary.Snapshot.Isolation)
+	}
+	fmt.Printf("Relations:          %d\n", summary.Relations)
+	fmt.Printf("Column statistics:  %d\n", summary.ColumnStatistics)
+	fmt.Printf("Files:              %s\n", strings.Join(summary.Files, " "))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+// connFlags are the connection, password and profile flags of the commands
+// connecting to a database.
+type connFlags struct {
+	fs           *flag.FlagSet
+	config       *dump.Config
+	password     dump.PasswordOptions
+	profilesPath string
+	profile      string
+}
+
+func addConnFlags(fs *flag.FlagSet, config *dump.Config) *connFlags {
+	c := &connFlags{fs: fs, config: config}
+	fs.StringVar(&c.profilesPath, "config", "", "Config file of named profiles (default: "+dump.ProfilesFile+" in the user config directory)")
+	fs.StringVar(&c.profile, "profile", "", "Profile of the config file providing the flags not given on the command line")
+
+	fs.StringVar(&config.ConnString, "conn", "", "Connection URI or keyword/value string, as accepted by libpq; the other connection flags override it")
+	fs.StringVar(&config.Host, "h", "", "Hostname or IP address (default: PGHOST or the libpq default)")
+	fs.IntVar(&config.Port, "p", 0, "Port number (default: PGPORT, 5433 in YB mode, 5432 otherwise)")
+	fs.StringVar(&config.Database, "d", "", "Database name or connection string (default: PGDATABASE or the user name)")
+	fs.StringVar(&config.User, "u", "", "Username (default: PGUSER or the OS user)")
+	// The password is never taken on the command line. Without any of
+	// these, PGPASSWORD and the password file are used.
+	fs.BoolVar(&c.password.Prompt, "W", false, "Prompt for the password, like psql -W")
+	fs.StringVar(&c.password.File, "password_file", "", "Read the password from the first line of a file")
+	fs.StringVar(&c.password.Env, "password_env", "", "Read the password from the named environment variable")
+	fs.BoolVar(&c.password.Stdin, "password_stdin", false, "Read the password from the first line of standard input")
+	return c
+}
+
+// resolve sets the flags not given on the command line from the profile,
+// then reads the password. With strict, profile options that are not
+// flags of the command are rejected rather than ignored, so that the dump
+// command catches typos.
+func (c *connFlags) resolve(strict bool) error {
+	if c.profile != "" {
+		if err := applyProfile(c.fs, c.profilesPath, c.profile, strict); err != nil {
+			return err
+		}
+	}
+	password, err := dump.ReadPassword(c.password)
+	if err != nil {
+		return err
+	}
+	c.config.Password = password
+	return nil
+}
+
+// applyProfile sets the flags of fs not given on the command line from a
+// profile.
+func applyProfile(fs *flag.FlagSet, path, name string, strict bool) error {
+	if path == "" {
+		var err error
+		if path, err = dump.DefaultProfilesPath(); err != nil {
+			return err
+		}
+	}
+	values, err := dump.LoadProfile(path, name)
+	if err != nil {
+		return err
+	}
+	var names map[string]string
+	if !strict {
+		names = make(map[string]string)
+		fs.VisitAll(func(f *flag.Flag) {
+			names[f.Name] = f.Name
+		})
+	}
+	return dump.ApplyProfile(fs, values, names)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+var importCommand = &command{
+	name:    "import",
+	args:    "DUMP_DIR",
+	summary: "Create the schema of a dump and import its statistics and planner settings into a database.",
+	setup: func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+		config := dump.Config{}
+		conn := addConnFlags(fs, &config)
+		opts := dump.ImportOptions{}
+		var maxRetries int
+		fs.BoolVar(&config.YBMode, "yb_mode", false, "Use YugabyteDB mode")
+		fs.BoolVar(&opts.SkipDDL, "skip_ddl", false, "Import into an existing schema, without running create_extensions.sql and ddl.sql")
+		fs.BoolVar(&opts.ExtendedStatistics, "ext_stats", true, "Also import the extended statistics, when they were dumped")
+		fs.BoolVar(&opts.Relpages, "relpages", false, "Also import the relpages estimated from DocDB tablet sizes, when they were dumped")
+		fs.BoolVar(&opts.Verify, "verify", false, "Check the statistics from a new session after the import")
+		fs.IntVar(&opts.BatchSize, "batch_size", dump.DefaultImportBatchSize, "Number of statements imported per transaction")
+		fs.IntVar(&maxRetries, "max_retries", dump.DefaultMaxRetries, "Retries of a batch failing with a transient error")
+		fs.BoolVar(&opts.Verbose, "v", false, "Verbose output")
+
+		return func(ctx context.Context, args []string) (any, error) {
+			dir, err := dumpDirArg(args)
+			if err != nil {
+				return nil, err
+			}
+			if err := conn.resolve(false); err != nil {
+				return nil, err
+			}
+			opts.YBMode = config.YBMode
+			opts.Retrier = dump.NewRetrier(maxRetries, opts.Verbose)
+			if err := dump.ImportDump(ctx, config, dir, opts); err != nil {
+				return nil, err
+			}
+			fmt.Printf("Imported %s.\n", dir)
+			return map[string]any{"dir": dir, "verified": opts.Verify}, nil
+		}
+	},
+}
+
+var verifyCommand = &command{
+	name:    "verify",
+	args:    "DUMP_DIR",
+	summary: "Check that the statistics of a dump are visible in a new session of the database they were imported into.",
+	setup: func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+		config := dump.Config{}
+		conn := addConnFlags(fs, &config)
+		fs.BoolVar(&config.YBMode, "yb_mode", false, "Use YugabyteDB mode")
+
+		return func(ctx context.Context, args []string) (any, error) {
+			dir, err := dumpDirArg(args)
+			if err != nil {
+				return nil, err
+			}
+			if err := conn.resolve(false); err != nil {
+				return nil, err
+			}
+			db, err := dump.Connect(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			defer db.Close(context.Background())
+			if err := dump.VerifyImportedStatistics(ctx, db, dir); err != nil {
+				return nil, err
+			}
+			fmt.Println("Imported statistics are visible.")
+			return map[string]any{"dir": dir}, nil
+		}
+	},
+}
+
+// replayResult is the result of the replay command.
+type replayResult struct {
+	Plan         []string `json:"plan"`
+	ExpectedPlan []string `json:"expected_plan,omitempty"`
+	Matches      bool     `json:"matches"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+var replayCommand = &command{
+	name:    "replay",
+	args:    "DUMP_DIR",
+	summary: "EXPLAIN the query of a dump on the database it was imported into and compare the plan with the dumped one.",
+	setup: func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+		config := dump.Config{}
+		conn := addConnFlags(fs, &config)
+		fs.StringVar(&config.QueryFile, "q", "", "Query file path, the one the dump was taken with")
+		fs.BoolVar(&config.YBMode, "yb_mode", false, "Use YugabyteDB mode")
+		fs.BoolVar(&config.EnableBaseScansCostModel, "enable_base_scans_cost_model", false, "Enable base scans cost model")
+		fs.DurationVar(&config.StatementTimeout, "statement_timeout", dump.DefaultStatementTimeout, "statement_timeout of the EXPLAIN transaction, 0 keeps the server setting")
+		fs.DurationVar(&config.LockTimeout, "lock_timeout", dump.DefaultLockTimeout, "lock_timeout of the EXPLAIN transaction, 0 keeps the server setting")
+		fs.BoolVar(&config.AllowNonSelect, "allow_non_select", false, "Accept query files that are not a single SELECT or WITH statement")
+
+		return func(ctx context.Context, args []string) (any, error) {
+			dir, err := dumpDirArg(args)
+			if err != nil {
+				return nil, err
+			}
+			if config.QueryFile == "" {
+				return nil, usageErrorf("query file is required")
+			}
+			if err := conn.resolve(false); err != nil {
+				return nil, err
+			}
+			expected, err := dump.ReadQueryPlan(dir)
+			if err != nil {
+				return nil, err
+			}
+
+			db, err := dump.Connect(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			defer db.Close(context.Background())
+			plan, warnings, err := dump.ReplayQueryPlan(ctx, db, dir, config)
+			for _, w := range warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			result := &replayResult{Plan: plan, Warnings: warnings}
+			want, got := strings.Join(expected, "\n"), strings.Join(plan, "\n")
+			if want == got {
+				result.Matches = true
+				fmt.Println(got)
+				return result, nil
+			}
+			result.ExpectedPlan = expected
+			dmp := diffmatchpatch.New()
+			fmt.Println(dmp.DiffPrettyText(dmp.DiffMain(want, got, false)))
+			return result, fmt.Errorf("%w: the plan differs from %s", errMismatch, dump.QueryPlanFile)
+		}
+	},
+}
+
+// dumpDirArg returns the dump directory of commands taking only that
+// argument.
+func dumpDirArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usageErrorf("expected a dump directory, got %d arguments", len(args))
+	}
+	return args[0], nil
+}
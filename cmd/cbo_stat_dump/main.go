@@ -1,46 +1,211 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/yugabyte/cbo_stat_dump/internal/dump"
 )
 
-func main() {
-	config := dump.Config{}
+// Exit codes, the same for every command.
+const (
+	exitOK = 0
+	// exitError reports a failed command.
+	exitError = 1
+	// exitUsage reports invalid flags or arguments.
+	exitUsage = 2
+	// exitMismatch reports a command that ran but found differences: diff
+	// between statistics, verify with statistics not visible, replay with
+	// another plan.
+	exitMismatch = 3
+)
+
+// errMismatch is wrapped by the errors of commands finding differences.
+var errMismatch = errors.New("differences found")
+
+// usageError reports invalid arguments, after which the usage of the
+// command is printed.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
 
-	flag.StringVar(&config.Host, "h", "localhost", "Hostname or IP address")
-	flag.IntVar(&config.Port, "p", 5433, "Port number")
-	flag.StringVar(&config.Database, "d", "", "Database name")
-	flag.StringVar(&config.User, "u", "", "Username")
-	flag.StringVar(&config.Password, "W", "", "Password") // Note: -W is actually a flag for prompt in psql, but here we take string? Python script uses argparse which implies string unless store_true. Python: '-W', '--password'.
-	// Wait, python script says:
-	// parser.add_argument('-W', '--password', help='Password')
+// command is a subcommand of cbo_stat_dump.
+type command struct {
+	name string
+	// args is the synopsis of the positional arguments.
+	args    string
+	summary string
+	// setup registers the flags of the command and returns the function
+	// running it with the positional arguments. The result is printed by
+	// -json; the command prints its human readable output itself.
+	setup func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error)
+}
 
-	flag.StringVar(&config.OutputDir, "o", "", "Output directory")
-	flag.StringVar(&config.QueryFile, "q", "", "Query file path")
-	flag.BoolVar(&config.YBMode, "yb_mode", false, "Use YugabyteDB mode")
-	flag.BoolVar(&config.EnableBaseScansCostModel, "enable_base_scans_cost_model", false, "Enable base scans cost model")
-	flag.BoolVar(&config.Verbose, "v", false, "Verbose output")
+var commands = []*command{
+	dumpCommand,
+	importCommand,
+	renderCommand,
+	diffCommand,
+	inspectCommand,
+	verifyCommand,
+	replayCommand,
+}
 
-	flag.Parse()
+// jsonOutput is set by -json, with which standard output holds only the
+// JSON result.
+var jsonOutput bool
 
-	if config.Database == "" || config.User == "" {
-		fmt.Println("Database and User are required.")
-		flag.Usage()
-		os.Exit(1)
+// jsonResult is printed on standard output by -json.
+type jsonResult struct {
+	Command  string `json:"command"`
+	OK       bool   `json:"ok"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	Result   any    `json:"result,omitempty"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	// The flag-only invocation of earlier versions is an alias for dump.
+	name := "dump"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		return help(args)
+	}
+	cmd := lookupCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", name)
+		printUsage()
+		return exitUsage
 	}
 
-	if config.OutputDir == "" {
-		fmt.Println("Output directory is required.")
-		flag.Usage()
-		os.Exit(1)
+	fs := flag.NewFlagSet("cbo_stat_dump "+cmd.name, flag.ContinueOnError)
+	jsonOutput = false
+	fs.BoolVar(&jsonOutput, "json", false, "Print the result as a JSON object on standard output, and everything else on standard error")
+	runCmd := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cbo_stat_dump %s [flags] %s\n\n%s\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
 	}
 
-	if err := dump.Run(config); err != nil {
+	stdout := os.Stdout
+	var result any
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		// The flag package printed the error and the usage.
+		err = &usageError{msg: err.Error()}
+	} else {
+		if jsonOutput {
+			// Keep standard output for the JSON result.
+			os.Stdout = os.Stderr
+			defer func() { os.Stdout = stdout }()
+		}
+		// SIGINT and SIGTERM cancel the command; a dump marks its output
+		// directory as incomplete. A second signal exits at once.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			stop()
+		}()
+		result, err = runCmd(ctx, fs.Args())
+		var uerr *usageError
+		if errors.As(err, &uerr) && !jsonOutput {
+			fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+			fs.Usage()
+		}
+	}
+
+	code := exitCode(err)
+	if jsonOutput {
+		out := jsonResult{Command: cmd.name, OK: err == nil, ExitCode: code, Result: result}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write JSON result: %v\n", err)
+			return exitError
+		}
+		return code
+	}
+	switch code {
+	case exitError:
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	case exitMismatch:
+		fmt.Fprintln(os.Stderr, err)
+	}
+	return code
+}
+
+// exitCode returns the exit code of a command failing with err.
+func exitCode(err error) int {
+	var uerr *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &uerr):
+		return exitUsage
+	case errors.Is(err, errMismatch), errors.Is(err, dump.ErrStatisticsNotVisible):
+		return exitMismatch
+	}
+	return exitError
+}
+
+func lookupCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// help prints the usage of cbo_stat_dump, or of the named command.
+func help(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitOK
+	}
+	cmd := lookupCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command %q.\n\n", args[0])
+		printUsage()
+		return exitUsage
+	}
+	run([]string{cmd.name, "-help"})
+	return exitOK
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: cbo_stat_dump <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nWithout a command, the flags are those of dump. Run \"cbo_stat_dump help <command>\" for the flags of a command.\n")
+	fmt.Fprintf(os.Stderr, "Exit codes: %d success, %d error, %d usage error, %d differences found.\n", exitOK, exitError, exitUsage, exitMismatch)
 }
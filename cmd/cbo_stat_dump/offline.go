@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
+)
+
+// The commands below read dump directories without connecting.
+
+var renderCommand = &command{
+	name:    "render",
+	args:    "DUMP_DIR",
+	summary: "Render the statistics of a dump again in another format, or its DDL converted to another dialect.",
+	setup: func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+		var ybMode bool
+		var format, output, ddlTarget, ddlKeyPolicy string
+		fs.BoolVar(&ybMode, "yb_mode", false, "Render the statistics import SQL for YugabyteDB")
+		fs.StringVar(&format, "format", "", "Statistics format: "+strings.Join(dump.RenderFormats, ", ")+" (default: yb with -yb_mode, else pg)")
+		fs.StringVar(&output, "o", "", "Output file (default: standard output)")
+		fs.StringVar(&ddlTarget, "ddl_target", "", "Render ddl.sql converted to this dialect, pg or yb, instead of the statistics")
+		fs.StringVar(&ddlKeyPolicy, "ddl_key_policy", dump.KeyPolicyASC, "Sharding of key columns without an ordering when converting to yb: asc, hash or keep")
+
+		return func(ctx context.Context, args []string) (any, error) {
+			dir, err := dumpDirArg(args)
+			if err != nil {
+				return nil, err
+			}
+			if err := validateDDLTarget(ddlTarget, ddlKeyPolicy); err != nil {
+				return nil, err
+			}
+			if ddlTarget != "" && format != "" {
+				return nil, usageErrorf("-format applies to statistics, not to the DDL rendered with -ddl_target")
+			}
+			if output == "" && jsonOutput {
+				return nil, usageErrorf("-json needs -o, standard output holds the JSON result")
+			}
+
+			var buf bytes.Buffer
+			if ddlTarget != "" {
+				ddl, err := os.ReadFile(filepath.Join(dir, dump.DDLFile))
+				if err != nil {
+					return nil, fmt.Errorf("failed to read %s: %w", dump.DDLFile, err)
+				}
+				converted, warnings := dump.TransformDDL(string(ddl), dump.DDLTransformOptions{Target: ddlTarget, KeyPolicy: ddlKeyPolicy})
+				for _, w := range warnings {
+					fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+				}
+				buf.WriteString(converted)
+			} else {
+				if format == "" {
+					format = dump.CatalogFormat(ybMode)
+				}
+				renderer, err := dump.NewRenderer(format, &buf)
+				if err != nil {
+					return nil, usageErrorf("%v", err)
+				}
+				if err := dump.RenderStatistics(dir, renderer); err != nil {
+					return nil, err
+				}
+			}
+
+			if output == "" {
+				if _, err := io.Copy(os.Stdout, &buf); err != nil {
+					return nil, fmt.Errorf("failed to write output: %w", err)
+				}
+				return nil, nil
+			}
+			if err := os.WriteFile(output, buf.Bytes(), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", output, err)
+			}
+			return map[string]any{"output": output}, nil
+		}
+	},
+}
+
+var diffCommand = &command{
+	name:    "diff",
+	args:    "OLD_DUMP_DIR NEW_DUMP_DIR",
+	summary: "Compare the statistics of two dumps, exiting with 3 when they differ.",
+	setup: func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+		return func(ctx context.Context, args []string) (any, error) {
+			if len(args) != 2 {
+				return nil, usageErrorf("expected two dump directories, got %d arguments", len(args))
+			}
+			changes, err := dump.DiffStatistics(args[0], args[1])
+			if err != nil {
+				return nil, err
+			}
+			if changes == nil {
+				changes = []dump.StatisticsChange{}
+			}
+			for _, change := range changes {
+				switch change.Kind {
+				case "added":
+					fmt.Printf("+ %s\n", change.Object)
+				case "removed":
+					fmt.Printf("- %s\n", change.Object)
+				default:
+					fmt.Printf("~ %s: %s\n", change.Object, strings.Join(change.Fields, ", "))
+				}
+			}
+			if len(changes) > 0 {
+				return changes, fmt.Errorf("%w: %d objects changed", errMismatch, len(changes))
+			}
+			return changes, nil
+		}
+	},
+}
+
+var inspectCommand = &command{
+	name:    "inspect",
+	args:    "DUMP_DIR",
+	summary: "Summarize a dump: completeness, server version, snapshot, statistics counts and files.",
+	setup: func(fs *flag.FlagSet) func(ctx context.Context, args []string) (any, error) {
+		return func(ctx context.Context, args []string) (any, error) {
+			dir, err := dumpDirArg(args)
+			if err != nil {
+				return nil, err
+			}
+			summary, err := dump.InspectDump(dir)
+			if err != nil {
+				return nil, err
+			}
+			printSummary(summary)
+			return summary, nil
+		}
+	},
+}
@@ -1,17 +1,19 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib" // Use pgx as stdlib driver
+	"github.com/jackc/pgx/v5"
 	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/yugabyte/cbo_stat_dump/internal/dump"
 )
 
 // Global config
@@ -20,11 +22,13 @@ var (
 	ybMode          bool
 	benchmarkPath   string
 	createProdDB    bool
+	prodConn        string
 	prodHost        string
 	prodPort        int
 	prodUser        string
 	prodPassword    string
 	prodDatabase    string
+	testConn        string
 	testHost        string
 	testPort        int
 	testUser        string
@@ -34,6 +38,48 @@ var (
 	colocation      bool
 	outDir          string
 	debug           bool
+	relpagesSource  string
+
+	profilesPath     string
+	prodProfile      string
+	testProfile      string
+	prodPasswordFile string
+	testPasswordFile string
+	prodPasswordEnv  string
+	testPasswordEnv  string
+)
+
+// prodProfileFlags and testProfileFlags map the cbo_stat_dump options of a
+// profile to the flags of the production and test connections. The other
+// options of a production profile are passed to cbo_stat_dump; those of a
+// test profile, and unsupportedProfileOptions, are rejected.
+var (
+	prodProfileFlags = map[string]string{
+		"conn":                         "prod_conn",
+		"h":                            "prod_host",
+		"p":                            "prod_port",
+		"u":                            "prod_user",
+		"d":                            "prod_database",
+		"password_file":                "prod_password_file",
+		"password_env":                 "prod_password_env",
+		"yb_mode":                      "yb_mode",
+		"enable_base_scans_cost_model": "enable_base_scans_cost_model",
+	}
+	testProfileFlags = map[string]string{
+		"conn":          "test_conn",
+		"h":             "test_host",
+		"p":             "test_port",
+		"u":             "test_user",
+		"password_file": "test_password_file",
+		"password_env":  "test_password_env",
+	}
+	// unsupportedProfileOptions read the password from the terminal or
+	// standard input, which the runner does not share with cbo_stat_dump.
+	unsupportedProfileOptions = []string{"W", "password_stdin"}
+
+	// dumpProfileOptions are the options of the production profile passed
+	// to cbo_stat_dump as flags.
+	dumpProfileOptions map[string]string
 )
 
 func main() {
@@ -41,23 +87,72 @@ func main() {
 	flag.BoolVar(&ybMode, "yb_mode", false, "Use YugabyteDB mode")
 	flag.StringVar(&benchmarkPath, "benchmark_path", "", "Path to benchmark files")
 	flag.BoolVar(&createProdDB, "create_prod_db", false, "Create production database")
-	flag.StringVar(&prodHost, "prod_host", "localhost", "Production host")
-	flag.IntVar(&prodPort, "prod_port", 5432, "Production port")
-	flag.StringVar(&prodUser, "prod_user", "", "Production user")
-	flag.StringVar(&prodPassword, "prod_password", "", "Production password")
-	flag.StringVar(&prodDatabase, "prod_database", "", "Production database")
+	flag.StringVar(&prodConn, "prod_conn", "", "Production connection URI or keyword/value string, as accepted by libpq; the other production flags override it")
+	flag.StringVar(&prodHost, "prod_host", "", "Production host (default: localhost without -prod_conn)")
+	flag.IntVar(&prodPort, "prod_port", 0, "Production port (default: PGPORT, 5433 in YB mode, 5432 otherwise)")
+	flag.StringVar(&prodUser, "prod_user", "", "Production user (default: yugabyte in YB mode, postgres otherwise, without -prod_conn)")
+	flag.StringVar(&prodPassword, "prod_password", "", "Deprecated: production password, visible to other users; use -prod_password_file or -prod_password_env")
+	flag.StringVar(&prodDatabase, "prod_database", "", "Production database (default: <benchmark>_db)")
+	flag.StringVar(&testConn, "test_conn", "", "Test connection URI or keyword/value string (default: -prod_conn)")
 	flag.StringVar(&testHost, "test_host", "", "Test host")
 	flag.IntVar(&testPort, "test_port", 0, "Test port")
 	flag.StringVar(&testUser, "test_user", "", "Test user")
-	flag.StringVar(&testPassword, "test_password", "", "Test password")
+	flag.StringVar(&testPassword, "test_password", "", "Deprecated: test password, visible to other users; use -test_password_file or -test_password_env")
+	flag.StringVar(&prodPasswordFile, "prod_password_file", "", "File holding the production password")
+	flag.StringVar(&testPasswordFile, "test_password_file", "", "File holding the test password")
+	flag.StringVar(&prodPasswordEnv, "prod_password_env", "", "Environment variable holding the production password")
+	flag.StringVar(&testPasswordEnv, "test_password_env", "", "Environment variable holding the test password")
+	flag.StringVar(&profilesPath, "config", "", "cbo_stat_dump config file of named profiles")
+	flag.StringVar(&prodProfile, "prod_profile", "", "cbo_stat_dump profile of the production connection")
+	flag.StringVar(&testProfile, "test_profile", "", "cbo_stat_dump profile of the test connection")
 	flag.BoolVar(&ignoreRanTests, "ignore_ran_tests", false, "Ignore ran tests")
 	flag.BoolVar(&enableBaseScans, "enable_base_scans_cost_model", false, "Enable base scans cost model")
 	flag.BoolVar(&colocation, "colocation", false, "Enable colocation")
 	flag.StringVar(&outDir, "outdir", "", "Output directory")
 	flag.BoolVar(&debug, "d", false, "Debug mode")
+	flag.StringVar(&relpagesSource, "relpages_source", dump.RelpagesSourceRaw, "relpages to import in YB mode: raw (pg_class) or docdb (estimated from DocDB sizes)")
 
 	flag.Parse()
 
+	for _, p := range []struct {
+		name  string
+		flags map[string]string
+		prod  bool
+	}{{prodProfile, prodProfileFlags, true}, {testProfile, testProfileFlags, false}} {
+		if p.name == "" {
+			continue
+		}
+		rest, err := applyProfile(p.name, p.flags)
+		if err == nil && !p.prod && len(rest) > 0 {
+			err = fmt.Errorf("options %s only apply to a production profile", strings.Join(sortedKeys(rest), ", "))
+		}
+		if err != nil {
+			fmt.Printf("Failed to apply profile %s: %v\n", p.name, err)
+			os.Exit(1)
+		}
+		if p.prod {
+			dumpProfileOptions = rest
+		}
+	}
+	for _, p := range []struct {
+		flag     string
+		password *string
+		opts     dump.PasswordOptions
+	}{
+		{"prod_password", &prodPassword, dump.PasswordOptions{File: prodPasswordFile, Env: prodPasswordEnv}},
+		{"test_password", &testPassword, dump.PasswordOptions{File: testPasswordFile, Env: testPasswordEnv}},
+	} {
+		if *p.password != "" {
+			fmt.Printf("Warning: -%s is deprecated, the password shows in the process list and shell history; use -%s_file or -%s_env\n", p.flag, p.flag, p.flag)
+			continue
+		}
+		var err error
+		if *p.password, err = dump.ReadPassword(p.opts); err != nil {
+			fmt.Printf("Failed to read password: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	if benchmark == "" {
 		fmt.Println("Benchmark name is required (-b)")
 		os.Exit(1)
@@ -67,30 +162,35 @@ func main() {
 	if benchmarkPath == "" {
 		benchmarkPath = filepath.Join("test", benchmark)
 	}
-	if ybMode {
-		if prodUser == "" {
-			prodUser = "yugabyte"
+	// Without a connection string, connect as earlier versions did. The
+	// port is left to dump.Connect, which defaults to 5433 in YB mode.
+	if prodConn == "" {
+		if prodHost == "" {
+			prodHost = "localhost"
 		}
-		if prodPort == 5432 && !isFlagPassed("prod_port") {
-			prodPort = 5433
-		}
-	} else {
-		if prodUser == "" {
+		if prodUser == "" && ybMode {
+			prodUser = "yugabyte"
+		} else if prodUser == "" {
 			prodUser = "postgres" // Default changed from python script's 'gaurav'
 		}
 	}
 
-	if testHost == "" {
-		testHost = prodHost
-	}
-	if testPort == 0 {
-		testPort = prodPort
-	}
-	if testUser == "" {
-		testUser = prodUser
-	}
-	if testPassword == "" {
-		testPassword = prodPassword
+	// The test connection defaults to the production one, unless it has a
+	// connection string of its own.
+	if testConn == "" {
+		testConn = prodConn
+		if testHost == "" {
+			testHost = prodHost
+		}
+		if testPort == 0 {
+			testPort = prodPort
+		}
+		if testUser == "" {
+			testUser = prodUser
+		}
+		if testPassword == "" {
+			testPassword = prodPassword
+		}
 	}
 	if prodDatabase == "" {
 		prodDatabase = benchmark + "_db"
@@ -103,18 +203,76 @@ func main() {
 		fmt.Println("Colocation only supported in YB mode")
 		os.Exit(1)
 	}
+	if relpagesSource != dump.RelpagesSourceRaw && relpagesSource != dump.RelpagesSourceDocDB {
+		fmt.Printf("Invalid -relpages_source %q, expected %s or %s\n", relpagesSource, dump.RelpagesSourceRaw, dump.RelpagesSourceDocDB)
+		os.Exit(1)
+	}
+	if relpagesSource == dump.RelpagesSourceDocDB && !ybMode {
+		fmt.Println("DocDB relpages only supported in YB mode")
+		os.Exit(1)
+	}
 
 	run()
 }
 
-func isFlagPassed(name string) bool {
-	found := false
-	flag.Visit(func(f *flag.Flag) {
-		if f.Name == name {
-			found = true
+// applyProfile sets the flags not given on the command line from a
+// cbo_stat_dump profile, renamed through flags. It returns the options
+// missing from flags, and rejects unsupportedProfileOptions.
+func applyProfile(name string, flags map[string]string) (map[string]string, error) {
+	path := profilesPath
+	if path == "" {
+		var err error
+		if path, err = dump.DefaultProfilesPath(); err != nil {
+			return nil, err
 		}
-	})
-	return found
+	}
+	values, err := dump.LoadProfile(path, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range unsupportedProfileOptions {
+		if _, ok := values[option]; ok {
+			return nil, fmt.Errorf("option %q is not supported by the test runner", option)
+		}
+	}
+	rest := make(map[string]string)
+	for key, value := range values {
+		if _, ok := flags[key]; !ok {
+			rest[key] = value
+		}
+	}
+	return rest, dump.ApplyProfile(flag.CommandLine, values, flags)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// prodConfig and testConfig return the settings of the production and test
+// connections to dbName.
+func prodConfig(dbName string) dump.Config {
+	return dump.Config{ConnString: prodConn, Host: prodHost, Port: prodPort, User: prodUser,
+		Password: prodPassword, Database: dbName, YBMode: ybMode}
+}
+
+func testConfig(dbName string) dump.Config {
+	return dump.Config{ConnString: testConn, Host: testHost, Port: testPort, User: testUser,
+		Password: testPassword, Database: dbName, YBMode: ybMode}
+}
+
+// connect opens a connection with cfg, exiting on failure.
+func connect(cfg dump.Config) *pgx.Conn {
+	conn, err := dump.Connect(context.Background(), cfg)
+	if err != nil {
+		fmt.Printf("Failed to connect to %s: %v\n", cfg.Database, err)
+		os.Exit(1)
+	}
+	return conn
 }
 
 func run() {
@@ -155,12 +313,23 @@ func run() {
 
 		// Create Test DB
 		testDBName := fmt.Sprintf("%s_%s_test_db", benchmark, queryName)
-		dropDatabase(testHost, testPort, testUser, testPassword, testDBName)
-		createDatabase(testHost, testPort, testUser, testPassword, testDBName, colocation)
+		dropDatabase(testConfig("postgres"), testDBName)
+		locale, err := dump.ReadLocaleInfo(queryOutDir)
+		if err != nil {
+			fmt.Printf("Failed to read locale of %s: %v\n", queryName, err)
+			os.Exit(1)
+		}
+		createDatabase(testConfig("postgres"), testDBName, colocation, locale)
 
 		// Restore stats
+		runSQLOnTestDB(testDBName, filepath.Join(queryOutDir, "create_extensions.sql"))
 		runSQLOnTestDB(testDBName, filepath.Join(queryOutDir, "ddl.sql"))
-		runSQLOnTestDB(testDBName, filepath.Join(queryOutDir, "import_statistics.sql"))
+		if ybMode {
+			runSQLOnTestDB(testDBName, filepath.Join(queryOutDir, "import_topology.sql"))
+		}
+		runSQLOnTestDB(testDBName, filepath.Join(queryOutDir, "import_planner_options.sql"))
+		importStatistics(testDBName, queryOutDir)
+		verifyImportedStatistics(testDBName, queryOutDir)
 
 		if ybMode {
 			// Extended stats? Python script imports it if exists?
@@ -188,7 +357,7 @@ func run() {
 			failedQueries = append(failedQueries, fmt.Sprintf("%s : %s", f.Name(), filepath.Join(queryOutDir, "query_plan_diff.txt")))
 		}
 
-		dropDatabase(testHost, testPort, testUser, testPassword, testDBName)
+		dropDatabase(testConfig("postgres"), testDBName)
 	}
 
 	if len(failedQueries) > 0 {
@@ -210,25 +379,36 @@ func runCBOStatDump(outDir, queryFile string) {
 		bin = "cbo_stat_dump_bin" // expect in PATH or current
 	}
 
-	args := []string{
-		"-h", prodHost,
-		"-p", fmt.Sprintf("%d", prodPort),
-		"-d", prodDatabase,
-		"-u", prodUser,
-		"-o", outDir,
-		"-q", queryFile,
+	// The profile options are given first, so that those of the runner
+	// override them.
+	var args []string
+	for _, key := range sortedKeys(dumpProfileOptions) {
+		args = append(args, "-"+key+"="+dumpProfileOptions[key])
 	}
-	if prodPassword != "" {
-		args = append(args, "-W", prodPassword)
+	// The password goes in the environment, not on the command line.
+	cfg := prodConfig(prodDatabase)
+	connString, env, err := cfg.LibpqConnString()
+	if err != nil {
+		fmt.Printf("Invalid production connection: %v\n", err)
+		os.Exit(1)
 	}
+	args = append(args,
+		"-conn", connString,
+		"-o", outDir,
+		"-q", queryFile,
+	)
 	if enableBaseScans && ybMode {
 		args = append(args, "-enable_base_scans_cost_model")
 	}
 	if ybMode {
 		args = append(args, "-yb_mode")
 	}
+	if relpagesSource == dump.RelpagesSourceDocDB {
+		args = append(args, "-estimate_relpages")
+	}
 
 	cmd := exec.Command(bin, args...)
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
@@ -239,57 +419,149 @@ func runCBOStatDump(outDir, queryFile string) {
 
 func createProductionDatabase() {
 	// Drop and Create
-	dropDatabase(prodHost, prodPort, prodUser, prodPassword, prodDatabase)
-	createDatabase(prodHost, prodPort, prodUser, prodPassword, prodDatabase, colocation)
+	dropDatabase(prodConfig("postgres"), prodDatabase)
+	createDatabase(prodConfig("postgres"), prodDatabase, colocation, nil)
 
 	createSQL := filepath.Join(benchmarkPath, "create.sql")
 	if ybMode {
-		createSQL = filepath.Join(benchmarkPath, "create.yb.sql")
+		ybCreateSQL := filepath.Join(benchmarkPath, "create.yb.sql")
+		if _, err := os.Stat(ybCreateSQL); err == nil {
+			createSQL = ybCreateSQL
+		} else {
+			createSQL = transformCreateSQL(createSQL)
+		}
 	}
-	runSQL(prodHost, prodPort, prodUser, prodPassword, prodDatabase, createSQL)
+	runSQL(prodConfig(prodDatabase), createSQL)
 }
 
-func dropDatabase(host string, port int, user, password, dbName string) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/postgres?sslmode=disable", user, password, host, port)
-	db, err := sql.Open("pgx", connStr)
+// transformCreateSQL converts a benchmark's PostgreSQL create.sql for
+// YugabyteDB, for benchmarks without a create.yb.sql, and returns the path of
+// the converted file.
+func transformCreateSQL(createSQL string) string {
+	ddl, err := os.ReadFile(createSQL)
 	if err != nil {
-		fmt.Printf("Failed to connect to drop db: %v\n", err)
+		fmt.Printf("Failed to read %s: %v\n", createSQL, err)
 		os.Exit(1)
 	}
-	defer db.Close()
+	ybDDL, warnings := dump.TransformDDL(string(ddl), dump.DDLTransformOptions{Target: dump.DDLDialectYB, KeyPolicy: dump.KeyPolicyASC})
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
 
-	_, err = db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", dbName))
-	if err != nil {
-		fmt.Printf("Failed to drop database %s: %v\n", dbName, err)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		fmt.Printf("Failed to create %s: %v\n", outDir, err)
 		os.Exit(1)
 	}
+	path := filepath.Join(outDir, "create.yb.sql")
+	if err := os.WriteFile(path, []byte(ybDDL), 0644); err != nil {
+		fmt.Printf("Failed to write %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	return path
 }
 
-func createDatabase(host string, port int, user, password, dbName string, colocated bool) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/postgres?sslmode=disable", user, password, host, port)
-	db, err := sql.Open("pgx", connStr)
+// dropDatabase drops dbName from a connection with cfg, to another database.
+func dropDatabase(cfg dump.Config, dbName string) {
+	conn := connect(cfg)
+	defer conn.Close(context.Background())
+
+	_, err := conn.Exec(context.Background(), fmt.Sprintf("DROP DATABASE IF EXISTS %s", dbName))
 	if err != nil {
-		fmt.Printf("Failed to connect to create db: %v\n", err)
+		fmt.Printf("Failed to drop database %s: %v\n", dbName, err)
 		os.Exit(1)
 	}
-	defer db.Close()
+}
 
-	query := fmt.Sprintf("CREATE DATABASE %s", dbName)
+// createDatabase creates dbName. When locale is set, the database is created
+// with the same encoding and collation settings and the result is verified,
+// since statistics on text columns depend on them.
+func createDatabase(cfg dump.Config, dbName string, colocated bool, locale *dump.LocaleInfo) {
+	conn := connect(cfg)
+	defer conn.Close(context.Background())
+
+	var options []string
+	if locale != nil {
+		options = append(options, locale.CreateDatabaseOptions())
+	}
 	if colocated {
-		query += " WITH colocation = true"
+		options = append(options, "colocation = true")
+	}
+	query := fmt.Sprintf("CREATE DATABASE %s", dbName)
+	if len(options) > 0 {
+		query += " WITH " + strings.Join(options, " ")
 	}
-	_, err = db.Exec(query)
+	_, err := conn.Exec(context.Background(), query)
 	if err != nil {
 		fmt.Printf("Failed to create database %s: %v\n", dbName, err)
 		os.Exit(1)
 	}
+
+	if locale != nil {
+		cfg.Database = dbName
+		verifyDatabaseLocale(cfg, locale)
+	}
+}
+
+// verifyDatabaseLocale checks from a connection with cfg that its database
+// was created with the dumped locale, provider included.
+func verifyDatabaseLocale(cfg dump.Config, locale *dump.LocaleInfo) {
+	dbName := cfg.Database
+	conn := connect(cfg)
+	defer conn.Close(context.Background())
+
+	created, err := dump.QueryDatabaseLocale(context.Background(), conn)
+	if err != nil {
+		fmt.Printf("Failed to verify locale of database %s: %v\n", dbName, err)
+		os.Exit(1)
+	}
+	if diffs := locale.Mismatches(created); len(diffs) > 0 {
+		fmt.Printf("Database %s was not created with the dumped locale:\n  %s\n", dbName, strings.Join(diffs, "\n  "))
+		os.Exit(1)
+	}
+}
+
+// importStatistics runs import_statistics.sql in batches, retrying batches
+// that fail with transient errors such as YB catalog version mismatches.
+func importStatistics(dbName, queryOutDir string) {
+	conn := connect(testConfig(dbName))
+	defer conn.Close(context.Background())
+
+	retrier := dump.NewRetrier(dump.DefaultMaxRetries, debug)
+	files := []string{dump.ImportStatisticsSQLFile}
+	if relpagesSource == dump.RelpagesSourceDocDB {
+		files = append(files, dump.ImportRelpagesSQLFile)
+	}
+	for _, file := range files {
+		err := dump.ImportSQLFile(context.Background(), conn, filepath.Join(queryOutDir, file),
+			dump.DefaultImportBatchSize, retrier, "statistics import")
+		if err != nil {
+			fmt.Printf("Failed to import statistics into %s: %v\n", dbName, err)
+			os.Exit(1)
+		}
+	}
+	if debug {
+		fmt.Printf("Statistics import retries: %d\n", retrier.Retries("statistics import"))
+	}
+}
+
+// verifyImportedStatistics checks from a new session that the imported
+// statistics are visible, i.e. that the catalog version bump reached it.
+func verifyImportedStatistics(dbName, queryOutDir string) {
+	conn := connect(testConfig(dbName))
+	defer conn.Close(context.Background())
+
+	if err := dump.VerifyImportedStatistics(context.Background(), conn, queryOutDir); err != nil {
+		fmt.Printf("Statistics import into %s failed: %v\n", dbName, err)
+		os.Exit(1)
+	}
 }
 
 func runSQLOnTestDB(dbName, sqlFile string) {
-	runSQL(testHost, testPort, testUser, testPassword, dbName, sqlFile)
+	runSQL(testConfig(dbName), sqlFile)
 }
 
-func runSQL(host string, port int, user, password, dbName, sqlFile string) {
+// runSQL runs sqlFile with psql, or ysqlsh in YB mode, connected with cfg.
+func runSQL(cfg dump.Config, sqlFile string) {
 	// Use psql or ysqlsh
 	bin := "psql"
 	if ybMode {
@@ -301,8 +573,11 @@ func runSQL(host string, port int, user, password, dbName, sqlFile string) {
 		bin = "psql"
 	}
 
-	cmd := exec.Command(bin, "-h", host, "-p", fmt.Sprintf("%d", port), "-U", user, "-d", dbName, "-f", sqlFile)
-	cmd.Env = append(os.Environ(), "PGPASSWORD="+password)
+	cmd, err := cfg.LibpqCommand(context.Background(), bin, "-f", sqlFile)
+	if err != nil {
+		fmt.Printf("Invalid connection to %s: %v\n", cfg.Database, err)
+		os.Exit(1)
+	}
 
 	if output, err := cmd.CombinedOutput(); err != nil {
 		fmt.Printf("Failed to run SQL %s: %v\nOutput: %s\n", sqlFile, err, string(output))
@@ -311,21 +586,17 @@ func runSQL(host string, port int, user, password, dbName, sqlFile string) {
 }
 
 func exportQueryPlan(dbName, gucsFile, queryFile, outDir string) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", testUser, testPassword, testHost, testPort, dbName)
-	db, err := sql.Open("pgx", connStr)
-	if err != nil {
-		fmt.Printf("Failed to connect for explain: %v\n", err)
-		os.Exit(1)
-	}
-	defer db.Close()
+	ctx := context.Background()
+	conn := connect(testConfig(dbName))
+	defer conn.Close(ctx)
 
-	// Use a transaction to ensure we use the same connection for SET and EXPLAIN
-	tx, err := db.Begin()
+	// Use a transaction so that the SETs only last for the EXPLAIN
+	tx, err := conn.Begin(ctx)
 	if err != nil {
 		fmt.Printf("Failed to begin transaction: %v\n", err)
 		os.Exit(1)
 	}
-	defer tx.Rollback()
+	defer tx.Rollback(ctx)
 
 	if _, err := os.Stat(gucsFile); err == nil {
 		gucsBytes, _ := os.ReadFile(gucsFile)
@@ -338,10 +609,10 @@ func exportQueryPlan(dbName, gucsFile, queryFile, outDir string) {
 		lines := strings.Split(gucs, "\n")
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
-			if line == "" {
+			if line == "" || strings.HasPrefix(line, "--") {
 				continue
 			}
-			if _, err := tx.Exec(line); err != nil {
+			if _, err := tx.Exec(ctx, line); err != nil {
 				// Warning only? Some GUCs might fail if not supported in target DB version?
 				// But for self-test (same DB) it should pass.
 				fmt.Printf("Warning: Failed to set GUC: %s. Error: %v\n", line, err)
@@ -350,16 +621,16 @@ func exportQueryPlan(dbName, gucsFile, queryFile, outDir string) {
 	}
 
 	if enableBaseScans && ybMode {
-		tx.Exec("SET yb_enable_base_scans_cost_model=ON")
+		tx.Exec(ctx, "SET yb_enable_base_scans_cost_model=ON")
 	}
 	if !ybMode {
-		tx.Exec("SET enable_cbo_statistics_simulation=ON")
+		tx.Exec(ctx, "SET enable_cbo_statistics_simulation=ON")
 	}
 
 	queryBytes, _ := os.ReadFile(queryFile)
 	query := "EXPLAIN " + string(queryBytes)
 
-	rows, err := tx.Query(query)
+	rows, err := tx.Query(ctx, query)
 	if err != nil {
 		fmt.Printf("Failed to explain: %v\nQuery: %s\n", err, query)
 		os.Exit(1)
@@ -7,6 +7,8 @@ toolchain go1.24.11
 require (
 	github.com/jackc/pgx/v5 v5.7.6
 	github.com/sergi/go-diff v1.4.0
+	golang.org/x/sync v0.13.0
+	golang.org/x/term v0.31.0
 )
 
 require (
@@ -14,6 +16,6 @@ require (
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	golang.org/x/crypto v0.37.0 // indirect
-	golang.org/x/sync v0.13.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
 	golang.org/x/text v0.24.0 // indirect
 )
@@ -1,3 +0,0 @@
-package db
-
-// Database utilities will go here
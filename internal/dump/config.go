@@ -1,6 +1,18 @@
 package dump
 
+import (
+	"io"
+	"os"
+	"time"
+)
+
 type Config struct {
+	// ConnString is a libpq connection URI or keyword/value string; a
+	// Database holding one is read the same way. The other connection
+	// fields override its settings when set, and settings missing
+	// everywhere come from the PG* environment variables, the service file
+	// and the password file. A zero Port is unset.
+	ConnString               string
 	Host                     string
 	Port                     int
 	Database                 string
@@ -8,7 +20,76 @@ type Config struct {
 	Password                 string
 	OutputDir                string
 	QueryFile                string
+	AppRole                  string
 	YBMode                   bool
 	EnableBaseScansCostModel bool
 	Verbose                  bool
+
+	// Query is the query to analyze and EXPLAIN when there is no
+	// QueryFile.
+	Query string
+
+	// Log receives the progress messages and warnings; nil writes them to
+	// standard output.
+	Log io.Writer
+
+	// SkipDDL leaves ddl.sql out of the dump, for hosts without pg_dump.
+	SkipDDL bool
+
+	// DDLTarget converts ddl.sql to another dialect (DDLDialectPG or
+	// DDLDialectYB) using DDLKeyPolicy; empty keeps the source dialect.
+	DDLTarget    string
+	DDLKeyPolicy string
+
+	// StatisticsNDJSON writes statistics.ndjson, one row per line, instead
+	// of statistics.json.
+	StatisticsNDJSON bool
+
+	// EstimateRelpages exports, in YB mode, relpages estimated from the
+	// DocDB size of each relation beside the raw pg_class value.
+	EstimateRelpages bool
+
+	// MaxRetries bounds the retries of a catalog query failing with a
+	// transient error such as a YB catalog version mismatch.
+	MaxRetries int
+
+	// StatementTimeout and LockTimeout bound the statements of the read
+	// only capture transactions; zero keeps the server settings.
+	// AllowNonSelect lets a query file hold other statements than a single
+	// SELECT or WITH.
+	StatementTimeout time.Duration
+	LockTimeout      time.Duration
+	AllowNonSelect   bool
+
+	// PhaseTimeout bounds each phase of the dump, and PhaseTimeouts the
+	// phases it names (see Phases); zero means no limit.
+	PhaseTimeout  time.Duration
+	PhaseTimeouts map[string]time.Duration
+
+	// Jobs bounds the exporters, and the relation batches of an exporter,
+	// running concurrently. Relation lists longer than RelationBatchSize
+	// are queried in batches.
+	Jobs              int
+	RelationBatchSize int
+
+	// YugabyteDB web endpoints used to capture gflags. Endpoints are given as
+	// host:port or as URLs; when empty they are discovered via yb_servers().
+	MasterWebEndpoints    []string
+	TServerWebEndpoints   []string
+	MasterWebPort         int
+	TServerWebPort        int
+	WebTLS                bool
+	WebCACert             string
+	WebInsecureSkipVerify bool
+	WebUser               string
+	WebPassword           string
+	WebTimeout            time.Duration
+	GFlagsAll             bool
+}
+
+func (c *Config) logWriter() io.Writer {
+	if c.Log == nil {
+		return os.Stdout
+	}
+	return c.Log
 }
@@ -0,0 +1,294 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultYSQLPort is used in YB mode when no port is given anywhere.
+const DefaultYSQLPort = 5433
+
+// pgxOnlySettings are connection settings understood by pgx but not by
+// libpq, which are not passed to pg_dump and psql.
+var pgxOnlySettings = map[string]bool{
+	"servicefile":                true,
+	"statement_cache_capacity":   true,
+	"description_cache_capacity": true,
+	"default_query_exec_mode":    true,
+	"min_read_buffer_size":       true,
+}
+
+// isConnString reports whether s is a connection URI or a keyword/value
+// connection string rather than a plain database name, like libpq does for
+// the dbname setting.
+func isConnString(s string) bool {
+	return strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") || strings.Contains(s, "=")
+}
+
+// connSettings returns the connection settings of cfg: those of
+// ConnString, or of a Database holding a connection string, with the
+// connection fields set in cfg on top. Settings missing from both are left
+// to the PG* environment variables, the service file, the password file and
+// the libpq defaults, which pgx and pg_dump resolve alike.
+func (c *Config) connSettings() (map[string]string, error) {
+	settings := make(map[string]string)
+	merge := func(s string) error {
+		parsed, err := parseConnString(s)
+		if err != nil {
+			return err
+		}
+		for k, v := range parsed {
+			settings[k] = v
+		}
+		return nil
+	}
+	if c.ConnString != "" {
+		if err := merge(c.ConnString); err != nil {
+			return nil, err
+		}
+	}
+	database := c.Database
+	if isConnString(database) {
+		if err := merge(database); err != nil {
+			return nil, err
+		}
+		database = ""
+	}
+
+	for key, value := range map[string]string{
+		"host":     c.Host,
+		"dbname":   database,
+		"user":     c.User,
+		"password": c.Password,
+	} {
+		if value != "" {
+			settings[key] = value
+		}
+	}
+	if c.Port != 0 {
+		settings["port"] = strconv.Itoa(c.Port)
+	}
+
+	// YSQL listens on 5433, not on the libpq default port.
+	if c.YBMode && settings["port"] == "" && settings["service"] == "" && os.Getenv("PGPORT") == "" && os.Getenv("PGSERVICE") == "" {
+		settings["port"] = strconv.Itoa(DefaultYSQLPort)
+	}
+	return settings, nil
+}
+
+// parseConnString parses a postgres:// URI or a keyword/value connection
+// string into its settings.
+func parseConnString(s string) (map[string]string, error) {
+	if strings.HasPrefix(s, "postgres://") || strings.HasPrefix(s, "postgresql://") {
+		return parseConnURI(s)
+	}
+	return parseConnKeywords(s)
+}
+
+func parseConnURI(s string) (map[string]string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection URI: %w", err)
+	}
+	settings := make(map[string]string)
+	if u.User != nil {
+		if user := u.User.Username(); user != "" {
+			settings["user"] = user
+		}
+		if password, ok := u.User.Password(); ok {
+			settings["password"] = password
+		}
+	}
+	if u.Host != "" {
+		var hosts, ports []string
+		hasPort := false
+		for _, hostPort := range strings.Split(u.Host, ",") {
+			host, port, err := net.SplitHostPort(hostPort)
+			if err != nil {
+				host, port = strings.Trim(hostPort, "[]"), ""
+			}
+			hasPort = hasPort || port != ""
+			hosts = append(hosts, host)
+			ports = append(ports, port)
+		}
+		settings["host"] = strings.Join(hosts, ",")
+		if hasPort {
+			settings["port"] = strings.Join(ports, ",")
+		}
+	}
+	if dbname := strings.TrimPrefix(u.Path, "/"); dbname != "" {
+		settings["dbname"] = dbname
+	}
+	for key, values := range u.Query() {
+		settings[key] = values[len(values)-1]
+	}
+	return settings, nil
+}
+
+// parseConnKeywords parses a keyword/value connection string such as
+// "host=db1 dbname='my db'". Values may be single quoted; a backslash
+// escapes the next character.
+func parseConnKeywords(s string) (map[string]string, error) {
+	settings := make(map[string]string)
+	for {
+		s = strings.TrimLeft(s, " \t\n\r")
+		if s == "" {
+			return settings, nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("invalid connection string: missing \"=\" after %q", s)
+		}
+		key := strings.TrimSpace(s[:eq])
+		if key == "" || strings.ContainsAny(key, " \t\n\r") {
+			return nil, fmt.Errorf("invalid connection string: bad keyword %q", key)
+		}
+		s = strings.TrimLeft(s[eq+1:], " \t\n\r")
+
+		var value strings.Builder
+		quoted := strings.HasPrefix(s, "'")
+		if quoted {
+			s = s[1:]
+		}
+		i := 0
+		for ; i < len(s); i++ {
+			ch := s[i]
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				value.WriteByte(s[i])
+				continue
+			}
+			if quoted && ch == '\'' || !quoted && (ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r') {
+				break
+			}
+			value.WriteByte(ch)
+		}
+		if quoted {
+			if i >= len(s) {
+				return nil, fmt.Errorf("invalid connection string: unterminated quoted value of %q", key)
+			}
+			i++
+		}
+		settings[key] = value.String()
+		s = s[i:]
+	}
+}
+
+// formatConnString formats settings as a keyword/value connection string,
+// in key order.
+func formatConnString(settings map[string]string) string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		value := strings.ReplaceAll(settings[key], `\`, `\\`)
+		parts[i] = key + "='" + strings.ReplaceAll(value, "'", `\'`) + "'"
+	}
+	return strings.Join(parts, " ")
+}
+
+// UseConnConfig sets the connection fields of c to those of cc, the config
+// of a connection opened by the caller, so that pg_dump connects to the same
+// database. Only the first host of cc is kept.
+func (c *Config) UseConnConfig(cc *pgx.ConnConfig) {
+	c.ConnString = cc.ConnString()
+	c.Host = cc.Host
+	c.Port = int(cc.Port)
+	c.Database = cc.Database
+	c.User = cc.User
+	c.Password = cc.Password
+}
+
+// libpqConnString returns the connection string of libpq clients such as
+// pg_dump and psql: settings without the password, which is passed in the
+// environment, nor the pgx only settings. The service file pgx read is
+// passed as PGSERVICEFILE.
+func libpqConnString(settings map[string]string) (connString string, env []string) {
+	libpq := make(map[string]string)
+	for key, value := range settings {
+		switch {
+		case key == "password":
+		case key == "servicefile":
+			env = append(env, "PGSERVICEFILE="+value)
+		case pgxOnlySettings[key], strings.HasPrefix(key, "pool_"):
+		default:
+			libpq[key] = value
+		}
+	}
+	return formatConnString(libpq), env
+}
+
+// Connect opens a connection with the connection settings of cfg.
+func Connect(ctx context.Context, cfg Config) (*pgx.Conn, error) {
+	settings, err := cfg.connSettings()
+	if err != nil {
+		return nil, err
+	}
+	connConfig, err := pgx.ParseConfig(formatConnString(settings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return conn, nil
+}
+
+// LibpqConnString returns the connection settings of c for libpq clients
+// such as psql: a connection string without the password, and the
+// environment variables to run the client with, PGPASSWORD included. The
+// password, from the password file included, is resolved as pgx does it.
+func (c *Config) LibpqConnString() (connString string, env []string, err error) {
+	settings, err := c.connSettings()
+	if err != nil {
+		return "", nil, err
+	}
+	connConfig, err := pgx.ParseConfig(formatConnString(settings))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	connString, env = libpqConnString(settings)
+	if connConfig.Password != "" {
+		env = append(env, "PGPASSWORD="+connConfig.Password)
+	}
+	return connString, env, nil
+}
+
+// LibpqCommand returns a command running bin, a libpq client such as psql,
+// connected with the settings of c and followed by args.
+func (c *Config) LibpqCommand(ctx context.Context, bin string, args ...string) (*exec.Cmd, error) {
+	connString, env, err := c.LibpqConnString()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, bin, append([]string{"-d", connString}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd, nil
+}
+
+// webHost returns the host YB web endpoints are derived from when they are
+// not discovered: the host the dump is connected to, or localhost for a
+// Unix socket.
+func (d *Dumper) webHost() string {
+	host := d.config.Host
+	if cc := d.connConfig(); cc != nil {
+		host = cc.Host
+	}
+	if host == "" || strings.HasPrefix(host, "/") {
+		return "localhost"
+	}
+	return host
+}
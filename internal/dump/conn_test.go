@@ -0,0 +1,147 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestParseConnString(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected map[string]string
+	}{
+		{
+			"postgres://us%40er:p%40ss%2Fw@h1:5433,h2:5434/my%20db?sslmode=verify-full&sslrootcert=/etc/ca.pem",
+			map[string]string{
+				"user": "us@er", "password": "p@ss/w", "host": "h1,h2", "port": "5433,5434",
+				"dbname": "my db", "sslmode": "verify-full", "sslrootcert": "/etc/ca.pem",
+			},
+		},
+		{
+			`host=db1 dbname = 'my db' password='it\'s\\x' sslcert=/tmp/c\ d`,
+			map[string]string{"host": "db1", "dbname": "my db", "password": `it's\x`, "sslcert": "/tmp/c d"},
+		},
+		{"service=prod", map[string]string{"service": "prod"}},
+	}
+	for _, tt := range tests {
+		got, err := parseConnString(tt.in)
+		if err != nil {
+			t.Errorf("parseConnString(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseConnString(%q) = %v, expected %v", tt.in, got, tt.expected)
+		}
+		// Formatting round-trips.
+		if again, err := parseConnString(formatConnString(got)); err != nil || !reflect.DeepEqual(again, got) {
+			t.Errorf("round-trip of %q gave %v, %v", formatConnString(got), again, err)
+		}
+	}
+
+	for _, bad := range []string{"host", "host='db1", "=x"} {
+		if _, err := parseConnString(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestConnSettings(t *testing.T) {
+	t.Setenv("PGPORT", "")
+	t.Setenv("PGSERVICE", "")
+
+	cfg := Config{
+		ConnString: "postgresql://app@db1:6432/app?sslmode=require",
+		User:       "admin",
+		Password:   "p@ss word",
+	}
+	got, err := cfg.connSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"host": "db1", "port": "6432", "dbname": "app", "user": "admin", "password": "p@ss word", "sslmode": "require"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	pgConfig, err := pgconn.ParseConfig(formatConnString(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pgConfig.Host != "db1" || pgConfig.Port != 6432 || pgConfig.User != "admin" || pgConfig.Password != "p@ss word" || pgConfig.TLSConfig == nil {
+		t.Errorf("unexpected pgx config %+v", pgConfig)
+	}
+
+	// A connection string given as the database, like psql -d.
+	cfg = Config{Database: "host=db2 dbname=stats", Port: 5434}
+	got, _ = cfg.connSettings()
+	expected = map[string]string{"host": "db2", "dbname": "stats", "port": "5434"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// YSQL port in YB mode, unless given elsewhere.
+	cfg = Config{Database: "yugabyte", YBMode: true}
+	if got, _ = cfg.connSettings(); got["port"] != "5433" {
+		t.Errorf("expected the YSQL port in YB mode, got %v", got)
+	}
+	t.Setenv("PGPORT", "5432")
+	if got, _ = cfg.connSettings(); got["port"] != "" {
+		t.Errorf("expected PGPORT to be left to libpq, got %v", got)
+	}
+}
+
+func TestPgDumpConnection(t *testing.T) {
+	d := &Dumper{config: Config{
+		ConnString: "host=db1 sslmode=verify-ca sslrootcert=/etc/ca.pem servicefile=/etc/pg_service.conf pool_max_conns=4",
+		Password:   "secret",
+	}}
+	args, env, err := d.pgDumpArgs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(args, " "), "secret") {
+		t.Errorf("expected no password on the pg_dump command line, got %v", args)
+	}
+	i := slices.Index(args, "-d")
+	if i < 0 || args[i+1] != "host='db1' sslmode='verify-ca' sslrootcert='/etc/ca.pem'" {
+		t.Errorf("unexpected pg_dump connection %v", args)
+	}
+	if !slices.Contains(env, "PGPASSWORD=secret") || !slices.Contains(env, "PGSERVICEFILE=/etc/pg_service.conf") {
+		t.Errorf("unexpected pg_dump environment %v", env)
+	}
+}
+
+func TestLibpqConnString(t *testing.T) {
+	t.Setenv("PGPORT", "")
+	t.Setenv("PGSERVICE", "")
+	t.Setenv("PGPASSWORD", "")
+	passfile := filepath.Join(t.TempDir(), "pgpass")
+	if err := os.WriteFile(passfile, []byte("db1:5432:my/db:us@er:frompgpass\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PGPASSFILE", passfile)
+
+	// Names with the characters a hand-built URL gets wrong, and the
+	// password left to the password file.
+	cfg := Config{ConnString: "sslmode=require", Host: "db1", Port: 5432, User: "us@er", Database: "my/db"}
+	connString, env, err := cfg.LibpqConnString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "dbname='my/db' host='db1' port='5432' sslmode='require' user='us@er'"; connString != expected {
+		t.Errorf("expected %s, got %s", expected, connString)
+	}
+	if !slices.Contains(env, "PGPASSWORD=frompgpass") {
+		t.Errorf("expected the password file to be read, got %v", env)
+	}
+
+	cfg.Password = "secret"
+	if connString, env, _ = cfg.LibpqConnString(); strings.Contains(connString, "secret") || !slices.Contains(env, "PGPASSWORD=secret") {
+		t.Errorf("expected the password in the environment only, got %s, %v", connString, env)
+	}
+}
@@ -0,0 +1,409 @@
+package dump
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DDL dialects converted by TransformDDL.
+const (
+	DDLDialectPG = "pg"
+	DDLDialectYB = "yb"
+)
+
+// Key policies deciding how primary key, unique and index columns without an
+// explicit ordering are sharded on YugabyteDB.
+const (
+	// KeyPolicyASC range shards every key column, which keeps the plans of
+	// PostgreSQL B-tree indexes.
+	KeyPolicyASC = "asc"
+	// KeyPolicyHash hash shards the first key column, as YugabyteDB does
+	// by default, and range shards the others.
+	KeyPolicyHash = "hash"
+	// KeyPolicyKeep leaves key columns as they are.
+	KeyPolicyKeep = "keep"
+)
+
+type DDLTransformOptions struct {
+	Target    string
+	KeyPolicy string
+
+	// NumTablets adds SPLIT INTO to hash sharded tables and indexes when
+	// converting to YugabyteDB without a captured Layout.
+	NumTablets int
+	// Layout, when set, recreates the tablets, sharding and colocation
+	// captured from a YugabyteDB source.
+	Layout *YBTableLayout
+}
+
+var (
+	reLeadingComments = regexp.MustCompile(`^(?:\s*--[^\n]*\n)*\s*`)
+
+	reCreateTable      = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\b`)
+	reCreateIndex      = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\b`)
+	reAlterTable       = regexp.MustCompile(`(?is)^ALTER\s+TABLE\b`)
+	reCreateTablegroup = regexp.MustCompile(`(?is)^(?:CREATE|DROP|ALTER)\s+TABLEGROUP\b`)
+	reCatalogDML       = regexp.MustCompile(`(?is)^(?:UPDATE|INSERT\s+INTO|DELETE\s+FROM)\s+(?:pg_catalog\.)?(?:pg_class|pg_statistic|pg_statistic_ext_data)\b`)
+	reYBSysTablesGUC   = regexp.MustCompile(`(?is)^SET\s+yb_non_ddl_txn_for_sys_tables_allowed\b`)
+	reCatalogBump      = regexp.MustCompile(`(?is)^UPDATE\s+pg_yb_catalog_version\b`)
+
+	reUnlogged      = regexp.MustCompile(`(?i)\bUNLOGGED\s+`)
+	reKeyList       = regexp.MustCompile(`(?i)\b(?:PRIMARY\s+KEY|UNIQUE)\s*\(`)
+	reInlineKey     = regexp.MustCompile(`(?i)\bPRIMARY\s+KEY\b\s*(?:[,)]|$)`)
+	reIndexOn       = regexp.MustCompile(`(?is)\sON\s+(?:ONLY\s+)?(?:"(?:[^"]|"")*"|[\w$]+)(?:\.(?:"(?:[^"]|"")*"|[\w$]+))?\s*(?:USING\s+(\w+)\s*)?\(`)
+	reSplitInto     = regexp.MustCompile(`(?is)\s+SPLIT\s+INTO\s+\d+\s+TABLETS`)
+	reSplitAt       = regexp.MustCompile(`(?is)\s+SPLIT\s+AT\s+VALUES\s*\(`)
+	reTablegroup    = regexp.MustCompile(`(?i)\s+TABLEGROUP\s+(?:"(?:[^"]|"")*"|[\w$]+)`)
+	reWithOptions   = regexp.MustCompile(`(?i)\bWITH\s*\(`)
+	reExclude       = regexp.MustCompile(`(?i)\bEXCLUDE\s+USING\b`)
+	reClusterOn     = regexp.MustCompile(`(?i)\bCLUSTER\s+ON\b`)
+	reInherits      = regexp.MustCompile(`(?i)\)\s*INHERITS\s*\(`)
+	reKeyOrdering   = regexp.MustCompile(`(?is)^(.*?)(?:\s+(HASH|ASC|DESC))?(\s+NULLS\s+(?:FIRST|LAST))?$`)
+	reSimpleColumns = regexp.MustCompile(`^\s*(?:"(?:[^"]|"")*"|[\w$]+)(?:\s*,\s*(?:"(?:[^"]|"")*"|[\w$]+))*\s*$`)
+)
+
+// ybOnlyOptions are storage parameters PostgreSQL does not know.
+var ybOnlyOptions = map[string]bool{
+	"colocation":    true,
+	"colocated":     true,
+	"colocation_id": true,
+	"table_oid":     true,
+}
+
+// TransformDDL converts a DDL script, such as ddl.sql or a benchmark's
+// create.sql, between PostgreSQL and YugabyteDB. Towards YugabyteDB key
+// columns are sharded according to the key policy, statistics DML is wrapped
+// with the settings needed to write the catalog, and features YugabyteDB
+// does not support are removed. Towards PostgreSQL the YugabyteDB storage
+// clauses are stripped. It returns the converted script and warnings about
+// what was removed or cannot be converted.
+func TransformDDL(ddl string, opts DDLTransformOptions) (string, []string) {
+	if opts.KeyPolicy == "" {
+		opts.KeyPolicy = KeyPolicyASC
+	}
+
+	var warnings []string
+	if opts.Target == DDLDialectYB {
+		if opts.Layout != nil {
+			var layoutWarnings []string
+			ddl, layoutWarnings = applyYBTableLayout(ddl, opts.Layout)
+			warnings = append(warnings, layoutWarnings...)
+		} else {
+			ddl = joinDDLStatements(foldPrimaryKeys(splitDDLStatements(ddl)))
+		}
+	}
+
+	var out []string
+	catalogDML := false
+	skipIf := 0
+	for _, stmt := range splitSQLScript(ddl) {
+		body := reLeadingComments.ReplaceAllString(stmt.text, "")
+
+		// The colocation check of applyYBTableLayout only makes sense on
+		// YugabyteDB.
+		if opts.Target == DDLDialectPG && stmt.meta {
+			if skipIf > 0 || strings.Contains(body, "cbo_colocation_differs") {
+				if strings.HasPrefix(body, `\if`) {
+					skipIf++
+				} else if strings.HasPrefix(body, `\endif`) {
+					skipIf--
+				}
+				continue
+			}
+		}
+
+		var text string
+		var w []string
+		switch opts.Target {
+		case DDLDialectYB:
+			if reYBSysTablesGUC.MatchString(body) || reCatalogBump.MatchString(body) {
+				continue
+			}
+			if reCatalogDML.MatchString(body) {
+				if !catalogDML {
+					out = append(out, "SET yb_non_ddl_txn_for_sys_tables_allowed = ON;")
+					catalogDML = true
+				}
+				out = append(out, stmt.text)
+				continue
+			}
+			text, w = toYBStatement(stmt.text, body, opts)
+		case DDLDialectPG:
+			if reYBSysTablesGUC.MatchString(body) || reCatalogBump.MatchString(body) {
+				continue
+			}
+			text, w = toPGStatement(stmt.text, body)
+		default:
+			text = stmt.text
+		}
+		warnings = append(warnings, w...)
+		if text != "" {
+			out = append(out, text)
+		}
+	}
+	if catalogDML {
+		out = append(out, strings.TrimSuffix(ybCatalogVersionBumpSQL, "\n"), "SET yb_non_ddl_txn_for_sys_tables_allowed = OFF;")
+	}
+
+	if len(out) == 0 {
+		return "", warnings
+	}
+	return strings.Join(out, "\n") + "\n", warnings
+}
+
+// toYBStatement converts one statement to YugabyteDB. An empty result drops
+// the statement.
+func toYBStatement(text, body string, opts DDLTransformOptions) (string, []string) {
+	var warnings []string
+	summary := firstLine(body)
+
+	switch {
+	case reCreateTable.MatchString(body):
+		if loc := reUnlogged.FindStringIndex(body[:min(len(body), 40)]); loc != nil {
+			text = strings.Replace(text, body[loc[0]:loc[1]], "", 1)
+			warnings = append(warnings, fmt.Sprintf("UNLOGGED removed, YugabyteDB tables are always logged: %s", summary))
+		}
+		if reExclude.MatchString(body) {
+			warnings = append(warnings, fmt.Sprintf("exclusion constraints are not supported by YugabyteDB: %s", summary))
+		}
+		if reInherits.MatchString(body) {
+			warnings = append(warnings, fmt.Sprintf("table inheritance is not supported by YugabyteDB: %s", summary))
+		}
+		if opts.KeyPolicy == KeyPolicyASC && reInlineKey.MatchString(body) {
+			warnings = append(warnings, fmt.Sprintf("inline primary key keeps the YugabyteDB default HASH sharding: %s", summary))
+		}
+		text = transformKeyLists(text, DDLDialectYB, opts.KeyPolicy)
+		if opts.NumTablets > 0 && opts.Layout == nil {
+			text = addSplitInto(text, opts, &warnings, summary)
+		}
+	case reAlterTable.MatchString(body):
+		if reExclude.MatchString(body) || reClusterOn.MatchString(body) {
+			return "", []string{fmt.Sprintf("statement removed, not supported by YugabyteDB: %s", summary)}
+		}
+		text = transformKeyLists(text, DDLDialectYB, opts.KeyPolicy)
+	case reCreateIndex.MatchString(body):
+		loc := reIndexOn.FindStringSubmatchIndex(text)
+		if loc == nil {
+			break
+		}
+		policy := opts.KeyPolicy
+		if loc[2] >= 0 {
+			method := strings.ToLower(text[loc[2]:loc[3]])
+			switch method {
+			case "btree", "lsm":
+				text = text[:loc[2]] + "lsm" + text[loc[3]:]
+			case "hash":
+				text = text[:loc[2]] + "lsm" + text[loc[3]:]
+				policy = KeyPolicyHash
+				warnings = append(warnings, fmt.Sprintf("hash index converted to a hash sharded lsm index: %s", summary))
+			case "gin", "ybgin":
+				return text, nil
+			default:
+				return "", []string{fmt.Sprintf("index removed, access method %s is not supported by YugabyteDB: %s", method, summary)}
+			}
+			loc = reIndexOn.FindStringSubmatchIndex(text)
+		}
+		open := loc[1] - 1
+		end := closingParen(text, open)
+		if end < 0 {
+			break
+		}
+		text = text[:open+1] + transformKeyColumns(text[open+1:end], DDLDialectYB, policy) + text[end:]
+		if opts.NumTablets > 0 && opts.Layout == nil {
+			text = addSplitInto(text, DDLTransformOptions{KeyPolicy: policy, NumTablets: opts.NumTablets}, &warnings, summary)
+		}
+	}
+	return text, warnings
+}
+
+// addSplitInto pre-splits a table or index whose first key column is hash
+// sharded. Range sharded relations would need split points instead.
+func addSplitInto(text string, opts DDLTransformOptions, warnings *[]string, summary string) string {
+	if opts.KeyPolicy != KeyPolicyHash {
+		*warnings = append(*warnings, fmt.Sprintf("SPLIT INTO needs hash sharding, not added: %s", summary))
+		return text
+	}
+	if reCreateTable.MatchString(reLeadingComments.ReplaceAllString(text, "")) && !reKeyList.MatchString(text) && !reInlineKey.MatchString(text) {
+		return text
+	}
+	stmt := &ddlStatement{lines: strings.Split(text, "\n")}
+	addClause(stmt, fmt.Sprintf("SPLIT INTO %d TABLETS", opts.NumTablets))
+	return stmt.text()
+}
+
+// toPGStatement converts one statement to PostgreSQL. An empty result drops
+// the statement.
+func toPGStatement(text, body string) (string, []string) {
+	switch {
+	case reCreateTablegroup.MatchString(body):
+		return "", nil
+	case reCreateTable.MatchString(body), reAlterTable.MatchString(body):
+		text = transformKeyLists(text, DDLDialectPG, KeyPolicyKeep)
+	case reCreateIndex.MatchString(body):
+		if loc := reIndexOn.FindStringSubmatchIndex(text); loc != nil {
+			if loc[2] >= 0 {
+				switch strings.ToLower(text[loc[2]:loc[3]]) {
+				case "lsm":
+					text = text[:loc[2]] + "btree" + text[loc[3]:]
+				case "ybgin":
+					text = text[:loc[2]] + "gin" + text[loc[3]:]
+				}
+				loc = reIndexOn.FindStringSubmatchIndex(text)
+			}
+			open := loc[1] - 1
+			if end := closingParen(text, open); end >= 0 {
+				text = text[:open+1] + transformKeyColumns(text[open+1:end], DDLDialectPG, KeyPolicyKeep) + text[end:]
+			}
+		}
+	default:
+		return text, nil
+	}
+
+	text = reSplitInto.ReplaceAllString(text, "")
+	for loc := reSplitAt.FindStringIndex(text); loc != nil; loc = reSplitAt.FindStringIndex(text) {
+		end := closingParen(text, loc[1]-1)
+		if end < 0 {
+			break
+		}
+		text = text[:loc[0]] + text[end+1:]
+	}
+	text = reTablegroup.ReplaceAllString(text, "")
+	text = stripYBOptions(text)
+	return text, nil
+}
+
+// stripYBOptions removes YugabyteDB only storage parameters from WITH lists,
+// and the list itself when nothing is left.
+func stripYBOptions(text string) string {
+	for start := 0; ; {
+		loc := reWithOptions.FindStringIndex(text[start:])
+		if loc == nil {
+			return text
+		}
+		open := start + loc[1] - 1
+		end := closingParen(text, open)
+		if end < 0 {
+			return text
+		}
+		var kept []string
+		for _, opt := range splitTopLevel(text[open+1 : end]) {
+			name, _, _ := strings.Cut(opt, "=")
+			if !ybOnlyOptions[strings.ToLower(strings.TrimSpace(name))] {
+				kept = append(kept, strings.TrimSpace(opt))
+			}
+		}
+		if len(kept) > 0 {
+			text = text[:open+1] + strings.Join(kept, ", ") + text[end:]
+			start = open + 1
+			continue
+		}
+		withStart := start + loc[0]
+		for withStart > 0 && (text[withStart-1] == ' ' || text[withStart-1] == '\n') {
+			withStart--
+		}
+		text = text[:withStart] + text[end+1:]
+		start = withStart
+	}
+}
+
+// transformKeyLists converts the column lists of PRIMARY KEY and UNIQUE
+// constraints in a CREATE TABLE or ALTER TABLE statement.
+func transformKeyLists(text, target, policy string) string {
+	for start := 0; ; {
+		loc := reKeyList.FindStringIndex(text[start:])
+		if loc == nil {
+			return text
+		}
+		open := start + loc[1] - 1
+		end := closingParen(text, open)
+		if end < 0 {
+			return text
+		}
+		columns := transformKeyColumns(text[open+1:end], target, policy)
+		text = text[:open+1] + columns + text[end:]
+		start = open + 1 + len(columns)
+	}
+}
+
+// transformKeyColumns converts a key column list. Towards YugabyteDB columns
+// without an ordering get one from the policy; towards PostgreSQL HASH is
+// removed and a hash column group such as (a, b) HASH is expanded.
+func transformKeyColumns(list, target, policy string) string {
+	elems := splitTopLevel(list)
+	for i, elem := range elems {
+		m := reKeyOrdering.FindStringSubmatch(strings.TrimSpace(elem))
+		expr, ordering, nulls := m[1], strings.ToUpper(m[2]), m[3]
+		switch target {
+		case DDLDialectYB:
+			if ordering != "" || policy == KeyPolicyKeep {
+				elems[i] = strings.TrimSpace(elem)
+				continue
+			}
+			ordering = "ASC"
+			if policy == KeyPolicyHash && i == 0 {
+				ordering = "HASH"
+			}
+			elems[i] = expr + " " + ordering + nulls
+		case DDLDialectPG:
+			if ordering != "HASH" {
+				elems[i] = strings.TrimSpace(elem)
+				continue
+			}
+			if strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") && reSimpleColumns.MatchString(expr[1:len(expr)-1]) {
+				expr = strings.Join(splitTopLevel(expr[1:len(expr)-1]), ", ")
+			}
+			elems[i] = expr + nulls
+		}
+	}
+	for i := range elems {
+		elems[i] = strings.TrimSpace(elems[i])
+	}
+	return strings.Join(elems, ", ")
+}
+
+// closingParen returns the index of the parenthesis closing the one at open,
+// skipping quoted text, or -1.
+func closingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			q := s[i]
+			for i++; i < len(s) && s[i] != q; i++ {
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s at commas outside parentheses and quotes.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, last := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"':
+			q := s[i]
+			for i++; i < len(s) && s[i] != q; i++ {
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[last:i]))
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[last:]))
+}
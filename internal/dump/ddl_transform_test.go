@@ -0,0 +1,121 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTransformDDLBenchmarks checks that converting the PostgreSQL schema of
+// each benchmark reproduces its hand written YugabyteDB schema.
+func TestTransformDDLBenchmarks(t *testing.T) {
+	for _, benchmark := range []string{"basic", "tpch", "join_order_benchmark"} {
+		pg, err := os.ReadFile(filepath.Join("..", "..", "test", benchmark, "create.sql"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		yb, err := os.ReadFile(filepath.Join("..", "..", "test", benchmark, "create.yb.sql"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, warnings := TransformDDL(string(pg), DDLTransformOptions{Target: DDLDialectYB, KeyPolicy: KeyPolicyASC})
+		for _, w := range warnings {
+			// The hand written schemas keep inline primary keys as well.
+			if !strings.HasPrefix(w, "inline primary key") {
+				t.Errorf("%s: unexpected warning %s", benchmark, w)
+			}
+		}
+		if g, e := comparableStatements(got), comparableStatements(string(yb)); !reflect.DeepEqual(g, e) {
+			for i := range min(len(g), len(e)) {
+				if g[i] != e[i] {
+					t.Errorf("%s: statement %d differs:\n%s\nexpected:\n%s", benchmark, i, g[i], e[i])
+					break
+				}
+			}
+			if len(g) != len(e) {
+				t.Errorf("%s: got %d statements, expected %d", benchmark, len(g), len(e))
+			}
+		}
+	}
+}
+
+// comparableStatements normalizes whitespace and leaves out the catalog
+// version bump, whose form differs between versions.
+func comparableStatements(script string) []string {
+	var stmts []string
+	for _, stmt := range splitSQLStatements(script) {
+		if reCatalogBump.MatchString(stmt) {
+			continue
+		}
+		stmts = append(stmts, strings.Join(strings.Fields(stmt), " "))
+	}
+	return stmts
+}
+
+func TestTransformDDLToPG(t *testing.T) {
+	ybDDL, _ := applyYBTableLayout(testPgDumpDDL, &YBTableLayout{
+		DatabaseColocated: true,
+		Relations: []YBTableProperties{
+			{Nspname: "public", Relname: "orders", Relkind: "r", NumTablets: 3, NumHashKeyColumns: 1},
+			{Nspname: "public", Relname: "orders_note_idx", Relkind: "i", NumTablets: 2, NumHashKeyColumns: 1},
+			{Nspname: "public", Relname: "Line Items", Relkind: "r", NumTablets: 1, Tablegroup: "tg1"},
+		},
+	})
+	ybDDL += "CREATE INDEX pair_idx ON public.ranged USING lsm ((k, k) HASH, k DESC) SPLIT AT VALUES ((1), (2));\n"
+
+	got, warnings := TransformDDL(ybDDL, DDLTransformOptions{Target: DDLDialectPG})
+	if len(warnings) > 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	for _, unexpected := range []string{"HASH", "SPLIT", "TABLEGROUP", "colocation", "lsm", `\if`, "cbo_colocation_differs"} {
+		if strings.Contains(got, unexpected) {
+			t.Errorf("unexpected %q in:\n%s", unexpected, got)
+		}
+	}
+	for _, expected := range []string{
+		"    CONSTRAINT orders_pkey PRIMARY KEY (id)\n);\n",
+		"WITH (fillfactor='80');\n",
+		"USING btree (note) WHERE (note IS NOT NULL);\n",
+		"USING btree (k, k, k DESC);\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+}
+
+func TestTransformDDLToYB(t *testing.T) {
+	ddl := `CREATE UNLOGGED TABLE public.t (
+    a integer NOT NULL,
+    b text
+);
+ALTER TABLE ONLY public.t
+    ADD CONSTRAINT t_pkey PRIMARY KEY (a);
+CREATE INDEX t_b_idx ON public.t USING btree (b DESC NULLS LAST, lower(b));
+CREATE INDEX t_b_hash ON public.t USING hash (b);
+CREATE INDEX t_b_gist ON public.t USING gist (b);
+ALTER TABLE public.t CLUSTER ON t_b_idx;
+`
+	got, warnings := TransformDDL(ddl, DDLTransformOptions{Target: DDLDialectYB, KeyPolicy: KeyPolicyHash, NumTablets: 4})
+	for _, expected := range []string{
+		"CREATE TABLE public.t (",
+		"    CONSTRAINT t_pkey PRIMARY KEY (a HASH)\n) SPLIT INTO 4 TABLETS;\n",
+		"USING lsm (b DESC NULLS LAST, lower(b) ASC) SPLIT INTO 4 TABLETS;\n",
+		"USING lsm (b HASH) SPLIT INTO 4 TABLETS;\n",
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %q in:\n%s", expected, got)
+		}
+	}
+	for _, unexpected := range []string{"UNLOGGED", "gist", "CLUSTER ON", "ALTER TABLE ONLY"} {
+		if strings.Contains(got, unexpected) {
+			t.Errorf("unexpected %q in:\n%s", unexpected, got)
+		}
+	}
+	if len(warnings) != 4 {
+		t.Errorf("expected warnings for UNLOGGED, hash, gist and CLUSTER ON, got %v", warnings)
+	}
+}
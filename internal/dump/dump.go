@@ -3,58 +3,192 @@ package dump
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"sync"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func Run(cfg Config) error {
+// Run dumps the database described by cfg into cfg.OutputDir. The dump
+// stops when ctx is cancelled; the output directory is then left with an
+// INCOMPLETE marker, as after any failure.
+func Run(ctx context.Context, cfg Config) (err error) {
 	if cfg.Verbose {
-		fmt.Println("Starting cbo_stat_dump...")
+		fmt.Fprintf(cfg.logWriter(), "Starting cbo_stat_dump...\n")
 	}
 
 	// Create output directory
 	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
+	if err := markIncomplete(cfg.OutputDir, "dump in progress"); err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			err = clearIncomplete(cfg.OutputDir)
+			return
+		}
+		reason := "dump failed"
+		if ctx.Err() != nil {
+			reason = "dump interrupted"
+		}
+		if markErr := markIncomplete(cfg.OutputDir, fmt.Sprintf("%s: %v", reason, err)); markErr != nil {
+			fmt.Fprintf(cfg.logWriter(), "Warning: %v\n", markErr)
+		}
+	}()
 
-	connConfig, err := pgx.ParseConfig(fmt.Sprintf("postgres://%s:%s@%s:%d/%s", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database))
+	settings, err := cfg.connSettings()
+	if err != nil {
+		return err
+	}
+	poolConfig, err := pgxpool.ParseConfig(formatConnString(settings))
 	if err != nil {
 		return fmt.Errorf("failed to parse config: %w", err)
 	}
+	// The dump transaction, a connection per running exporter and one per
+	// relation batch those exporters run.
+	poolConfig.MaxConns = int32(2*max(cfg.Jobs, 1) + 1)
 
-	conn, err := pgx.ConnectConfig(context.Background(), connConfig)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer conn.Close(context.Background())
+	defer pool.Close()
 
-	dumper := NewDumper(conn, cfg)
+	dumper := NewDumper(pool, cfg)
 
-	return dumper.Dump()
+	return dumper.Dump(ctx)
 }
 
 type Dumper struct {
+	pool *pgxpool.Pool
+	// conn is the connection of a dump without a pool, see NewConnDumper.
 	conn   *pgx.Conn
 	config Config
+
+	// db runs the catalog reads: the dump transaction while Dump runs, or
+	// the transaction of a worker.
+	db       querier
+	tx       pgx.Tx
+	snapshot DumpSnapshot
+
+	// gflags is set by ExportGFlags in YB mode.
+	gflags *GFlagsDump
+	// ybLayout is set by ExportYBTableProperties in YB mode and applied to
+	// the exported DDL.
+	ybLayout *YBTableLayout
+
+	retrier *Retrier
+}
+
+func NewDumper(pool *pgxpool.Pool, cfg Config) *Dumper {
+	cfg.Log = &syncWriter{w: cfg.logWriter()}
+	d := &Dumper{
+		pool:    pool,
+		config:  cfg,
+		db:      pool,
+		retrier: NewRetrier(cfg.MaxRetries, cfg.Verbose),
+	}
+	d.retrier.Log = cfg.Log
+	return d
+}
+
+// NewConnDumper returns a Dumper reading the catalog on conn, a connection
+// owned by the caller. Everything runs in order on a single transaction,
+// which is rolled back when the dump ends.
+func NewConnDumper(conn *pgx.Conn, cfg Config) *Dumper {
+	cfg.Log = &syncWriter{w: cfg.logWriter()}
+	d := &Dumper{
+		conn:    conn,
+		config:  cfg,
+		retrier: NewRetrier(cfg.MaxRetries, cfg.Verbose),
+	}
+	d.retrier.Log = cfg.Log
+	return d
+}
+
+// syncWriter serializes the writes to the log of a dump, which the workers
+// of runTasks and queryChunked share.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// logf writes a progress message or a warning to the log of the dump.
+func (d *Dumper) logf(format string, args ...any) {
+	fmt.Fprintf(d.config.logWriter(), format, args...)
+}
+
+// connConfig returns the config of the connections of the dump.
+func (d *Dumper) connConfig() *pgx.ConnConfig {
+	if d.pool != nil {
+		return d.pool.Config().ConnConfig
+	}
+	if d.conn != nil {
+		return d.conn.Config()
+	}
+	return nil
 }
 
-func NewDumper(conn *pgx.Conn, cfg Config) *Dumper {
-	return &Dumper{
-		conn:   conn,
-		config: cfg,
+func (d *Dumper) Dump(ctx context.Context) error {
+	var conn txBeginner = d.conn
+	if d.pool != nil {
+		pooled, err := d.pool.Acquire(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to acquire connection: %w", err)
+		}
+		defer pooled.Release()
+		conn = pooled
+	}
+
+	// All catalog reads, pg_dump included, see the same snapshot.
+	err := d.runPhase(ctx, PhaseSnapshot, func(ctx context.Context) error {
+		return d.beginSnapshot(ctx, conn)
+	})
+	if err != nil {
+		return err
+	}
+	defer d.endSnapshot()
+
+	if err := d.dump(ctx); err != nil {
+		return err
+	}
+	if err := d.ExportSnapshot(); err != nil {
+		return fmt.Errorf("failed to export snapshot: %w", err)
+	}
+	if d.config.Verbose {
+		if summary := d.retrier.Summary(); summary != "" {
+			d.logf("Retries: %s\n", summary)
+		} else {
+			d.logf("Retries: none\n")
+		}
 	}
+	return nil
 }
 
-func (d *Dumper) Dump() error {
-	var relationNames []string
+func (d *Dumper) dump(ctx context.Context) error {
+	var relations []Relation
 	var err error
 
-	if d.config.QueryFile != "" {
+	// The query is analyzed on the dump transaction, where the planner
+	// settings it needs are set.
+	if d.config.QueryFile != "" || d.config.Query != "" {
 		if d.config.Verbose {
-			fmt.Println("Analyzing query file to identify relations...")
+			d.logf("Analyzing query file to identify relations...\n")
 		}
-		relationNames, err = d.GetRelationNamesInQuery(d.config.QueryFile)
+		err = d.runPhase(ctx, PhaseQueryAnalysis, func(ctx context.Context) error {
+			relations, err = d.GetRelationsInQuery(ctx, d.config.QueryFile)
+			return err
+		})
 		if err != nil {
 			return fmt.Errorf("failed to analyze query: %w", err)
 		}
@@ -63,63 +197,216 @@ func (d *Dumper) Dump() error {
 		// Python: export_query_file
 		// TODO: copy file to output dir
 
-		if err := d.ExportQueryPlan(d.config.QueryFile); err != nil {
+		err = d.runPhase(ctx, PhaseQueryPlan, func(ctx context.Context) error {
+			return d.ExportQueryPlan(ctx, d.config.QueryFile)
+		})
+		if err != nil {
 			return fmt.Errorf("failed to export query plan: %w", err)
 		}
 	}
 
-	if d.config.Verbose {
-		fmt.Println("Exporting DDL...")
+	// The table layout is applied to the DDL and used by the relpages
+	// estimate, so it is read before the other exporters start.
+	if d.config.YBMode {
+		if d.config.Verbose {
+			d.logf("Exporting Table Properties...\n")
+		}
+		err = d.runPhase(ctx, PhaseTableProperties, func(ctx context.Context) error {
+			return d.ExportYBTableProperties(ctx, relations)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to export table properties: %w", err)
+		}
 	}
-	if err := d.ExportDDL(relationNames); err != nil {
-		return fmt.Errorf("failed to export DDL: %w", err)
+
+	pgVer, err := d.serverVersionNum(ctx)
+	if err != nil {
+		return err
 	}
 
-	if d.config.Verbose {
-		fmt.Println("Exporting Statistics...")
+	tasks := []exportTask{
+		{"Extensions", PhaseExtensions, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportExtensions(ctx); err != nil {
+				return fmt.Errorf("failed to export extensions: %w", err)
+			}
+			return nil
+		}},
 	}
-	if err := d.ExportStatistics(relationNames); err != nil {
-		return fmt.Errorf("failed to export statistics: %w", err)
+	if d.config.YBMode {
+		tasks = append(tasks, exportTask{"Topology", PhaseTopology, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportYBTopology(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export topology: %w", err)
+			}
+			return nil
+		}})
 	}
-
-	if d.config.Verbose {
-		fmt.Println("Exporting Extended Statistics...")
+	if !d.config.SkipDDL {
+		tasks = append(tasks, exportTask{"DDL", PhaseDDL, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportDDL(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export DDL: %w", err)
+			}
+			return nil
+		}})
+	}
+	tasks = append(tasks,
+		exportTask{"Statistics", PhaseStatistics, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportStatistics(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export statistics: %w", err)
+			}
+			return nil
+		}},
+	)
+	if d.config.YBMode && d.config.EstimateRelpages {
+		tasks = append(tasks, exportTask{"DocDB Relpages", PhaseRelpages, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportYBRelpages(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export relpages: %w", err)
+			}
+			return nil
+		}})
 	}
-	// Check version first? ExportExtendedStatistics logic should probably check version or be safe?
 	// Python checks: if pg_major_version >= 15
-	var versionStr string
-	d.conn.QueryRow(context.Background(), "SHOW server_version_num").Scan(&versionStr)
-	var pgVer int
-	fmt.Sscanf(versionStr, "%d", &pgVer)
 	if pgVer >= 150000 {
-		if err := d.ExportExtendedStatistics(relationNames); err != nil {
-			return fmt.Errorf("failed to export extended statistics: %w", err)
-		}
+		tasks = append(tasks, exportTask{"Extended Statistics", PhaseExtendedStatistics, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportExtendedStatistics(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export extended statistics: %w", err)
+			}
+			return nil
+		}})
 	}
+	tasks = append(tasks,
+		exportTask{"Planner Options", PhasePlannerOptions, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportPlannerOptions(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export planner options: %w", err)
+			}
+			return nil
+		}},
+		exportTask{"Version", PhaseVersion, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportVersion(ctx); err != nil {
+				return fmt.Errorf("failed to export version: %w", err)
+			}
+			return nil
+		}},
+		exportTask{"Overridden GUCs", PhaseGUCs, func(ctx context.Context, w *Dumper) error {
+			// GFlags go before the GUCs, which merge in ysql_pg_conf_csv.
+			if w.config.YBMode {
+				if w.config.Verbose {
+					w.logf("Exporting GFlags...\n")
+				}
+				if err := w.ExportGFlags(ctx); err != nil {
+					// Non-critical
+					w.logf("Warning: %v\n", err)
+				}
+			}
+			if err := w.ExportOverriddenGUCs(ctx); err != nil {
+				return fmt.Errorf("failed to export GUCs: %w", err)
+			}
+			return nil
+		}},
+		exportTask{"Locale", PhaseLocale, func(ctx context.Context, w *Dumper) error {
+			if err := w.ExportLocale(ctx, relations); err != nil {
+				return fmt.Errorf("failed to export locale: %w", err)
+			}
+			return nil
+		}},
+	)
 
-	if d.config.Verbose {
-		fmt.Println("Exporting Version...")
-	}
-	if err := d.ExportVersion(); err != nil {
-		return fmt.Errorf("failed to export version: %w", err)
-	}
+	return d.runTasks(ctx, tasks)
+}
 
-	if d.config.Verbose {
-		fmt.Println("Exporting Overridden GUCs...")
+// serverVersionNum returns server_version_num, e.g. 150010 for 15.10.
+func (d *Dumper) serverVersionNum(ctx context.Context) (int, error) {
+	var versionStr string
+	if err := d.queryRow(ctx, "version", []any{&versionStr}, "SHOW server_version_num"); err != nil {
+		return 0, fmt.Errorf("failed to get server version: %w", err)
 	}
-	if err := d.ExportOverriddenGUCs(); err != nil {
-		return fmt.Errorf("failed to export GUCs: %w", err)
+	var pgVer int
+	fmt.Sscanf(versionStr, "%d", &pgVer)
+	return pgVer, nil
+}
+
+// retry runs fn with the retrier of d. Inside the dump transaction only the
+// errors a rerun in the same snapshot recovers from are retried; the others
+// fail the phase.
+func (d *Dumper) retry(ctx context.Context, phase string, fn func() error) error {
+	retryable := isRetryable
+	if d.tx != nil {
+		retryable = isRetryableInTransaction
 	}
+	return d.retrier.do(ctx, phase, retryable, fn)
+}
 
-	if d.config.YBMode {
-		if d.config.Verbose {
-			fmt.Println("Exporting GFlags...")
-		}
-		if err := d.ExportGFlags(); err != nil {
-			// Non-critical
-			fmt.Printf("Warning: %v\n", err)
-		}
+// queryRow runs a single row query and scans it into dest, retrying
+// transient failures under phase.
+func (d *Dumper) queryRow(ctx context.Context, phase string, dest []any, sql string, args ...any) error {
+	return d.retry(ctx, phase, func() error {
+		return d.attempt(ctx, func(q querier) error {
+			return q.QueryRow(ctx, sql, args...).Scan(dest...)
+		})
+	})
+}
+
+// queryAll runs sql and scans every row with scan. After a transient failure
+// the query is rerun from the start and the rows read so far are discarded.
+func queryAll[T any](ctx context.Context, d *Dumper, phase, sql string, args []any, scan func(pgx.Rows) (T, error)) ([]T, error) {
+	var result []T
+	err := d.retry(ctx, phase, func() error {
+		result = nil
+		return d.attempt(ctx, func(q querier) error {
+			rows, err := q.Query(ctx, sql, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				v, err := scan(rows)
+				if err != nil {
+					return err
+				}
+				result = append(result, v)
+			}
+			return rows.Err()
+		})
+	})
+	return result, err
+}
+
+// queryEach runs sql and passes every row to each as it is read. Rows are
+// expected to be written to out, which is rewound to where the query
+// started before a retry.
+func queryEach(ctx context.Context, d *Dumper, phase, sql string, args []any, out rowRewinder, each func(pgx.Rows) error) error {
+	if err := out.mark(); err != nil {
+		return err
 	}
+	return d.retry(ctx, phase, func() error {
+		if err := out.rewind(); err != nil {
+			return err
+		}
+		return d.attempt(ctx, func(q querier) error {
+			rows, err := q.Query(ctx, sql, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				if err := each(rows); err != nil {
+					return err
+				}
+			}
+			return rows.Err()
+		})
+	})
+}
 
-	return nil
+// scanString scans a single text column.
+func scanString(rows pgx.Rows) (string, error) {
+	var s string
+	err := rows.Scan(&s)
+	return s, err
+}
+
+// scanJSON scans a row_to_json row.
+func scanJSON(rows pgx.Rows) ([]byte, error) {
+	var jsonBytes []byte
+	err := rows.Scan(&jsonBytes)
+	return jsonBytes, err
 }
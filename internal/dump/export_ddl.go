@@ -1,6 +1,7 @@
 package dump
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,7 +10,45 @@ import (
 	"strings"
 )
 
-func (d *Dumper) ExportDDL(relationNames []string) error {
+// DDLFile is the schema dumped by pg_dump.
+const DDLFile = "ddl.sql"
+
+// pgDumpArgs returns the pg_dump arguments and the environment variables
+// it needs beside those of the process. pg_dump connects with the settings
+// of the dump and reads the catalog in the snapshot of the dump transaction
+// when it could be exported.
+func (d *Dumper) pgDumpArgs(relations []Relation) ([]string, []string, error) {
+	settings, err := d.config.connSettings()
+	if err != nil {
+		return nil, nil, err
+	}
+	connString, env := libpqConnString(settings)
+	// The password resolved by pgx, from the password file included.
+	password := settings["password"]
+	if cc := d.connConfig(); cc != nil {
+		password = cc.Password
+	}
+	if password != "" {
+		env = append(env, "PGPASSWORD="+password)
+	}
+
+	args := []string{
+		"-d", connString,
+		"-s", // Schema only
+	}
+	if d.snapshot.SnapshotID != "" {
+		args = append(args, "--snapshot="+d.snapshot.SnapshotID)
+	}
+	if d.config.LockTimeout > 0 {
+		args = append(args, fmt.Sprintf("--lock-wait-timeout=%d", d.config.LockTimeout.Milliseconds()))
+	}
+	for _, rel := range relations {
+		args = append(args, "-t", pgDumpPattern(rel.Schema, rel.Name))
+	}
+	return args, env, nil
+}
+
+func (d *Dumper) ExportDDL(ctx context.Context, relations []Relation) error {
 	// We assume pg_dump is in PATH.
 	// If YBMode is true, we might need yb_pg_dump equivalent?
 	// The python script uses 'pg_dump' or 'ysql_dump'?
@@ -21,25 +60,18 @@ func (d *Dumper) ExportDDL(relationNames []string) error {
 
 	pgDumpBin := "pg_dump" // Or customizable?
 
-	args := []string{
-		"-h", d.config.Host,
-		"-p", fmt.Sprintf("%d", d.config.Port),
-		"-d", d.config.Database,
-		"-U", d.config.User,
-		"-s", // Schema only
-	}
-
-	if len(relationNames) > 0 {
-		for _, rel := range relationNames {
-			args = append(args, "-t", rel)
-		}
+	args, env, err := d.pgDumpArgs(relations)
+	if err != nil {
+		return fmt.Errorf("failed to resolve pg_dump connection: %w", err)
 	}
-
-	// Password handling
-	cmd := exec.Command(pgDumpBin, args...)
-	cmd.Env = append(os.Environ(), "PGPASSWORD="+d.config.Password)
+	// pg_dump is killed when ctx is cancelled or its phase times out.
+	cmd := exec.CommandContext(ctx, pgDumpBin, args...)
+	cmd.Env = append(os.Environ(), env...)
 
 	output, err := cmd.CombinedOutput()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("pg_dump interrupted: %w", ctxErr)
+	}
 	if err != nil {
 		return fmt.Errorf("pg_dump failed: %s, output: %s", err, string(output))
 	}
@@ -62,8 +94,23 @@ func (d *Dumper) ExportDDL(relationNames []string) error {
 		}
 	}
 
-	outputPath := filepath.Join(d.config.OutputDir, "ddl.sql")
-	if err := os.WriteFile(outputPath, []byte(filteredOutput.String()), 0644); err != nil {
+	ddl := filteredOutput.String()
+	source := DDLDialectPG
+	if d.config.YBMode {
+		source = DDLDialectYB
+	}
+	var warnings []string
+	if d.config.DDLTarget != "" && d.config.DDLTarget != source {
+		ddl, warnings = TransformDDL(ddl, DDLTransformOptions{Target: d.config.DDLTarget, KeyPolicy: d.config.DDLKeyPolicy})
+	} else if d.ybLayout != nil {
+		ddl, warnings = applyYBTableLayout(ddl, d.ybLayout)
+	}
+	for _, w := range warnings {
+		d.logf("Warning: %s\n", w)
+	}
+
+	outputPath := filepath.Join(d.config.OutputDir, DDLFile)
+	if err := os.WriteFile(outputPath, []byte(ddl), 0644); err != nil {
 		return fmt.Errorf("failed to write ddl.sql: %w", err)
 	}
 
@@ -0,0 +1,132 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	ExtensionsJSONFile      = "extensions.json"
+	CreateExtensionsSQLFile = "create_extensions.sql"
+)
+
+// PreloadLibraryGUCs are the settings through which planner hooks can be
+// loaded without a matching pg_extension entry.
+var PreloadLibraryGUCs = []string{
+	"shared_preload_libraries",
+	"session_preload_libraries",
+	"local_preload_libraries",
+}
+
+type ExtensionsDump struct {
+	Version          string              `json:"version"`
+	Extensions       []Extension         `json:"extensions"`
+	PreloadLibraries map[string][]string `json:"preload_libraries"`
+}
+
+type Extension struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Schema  string `json:"schema"`
+}
+
+func (d *Dumper) ExportExtensions(ctx context.Context) error {
+	extensions, err := queryAll(ctx, d, "extensions", `
+		SELECT e.extname, e.extversion, n.nspname
+		FROM pg_extension e JOIN pg_namespace n ON n.oid = e.extnamespace
+		ORDER BY e.extname`, nil, func(rows pgx.Rows) (Extension, error) {
+		var ext Extension
+		err := rows.Scan(&ext.Name, &ext.Version, &ext.Schema)
+		return ext, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pg_extension: %w", err)
+	}
+
+	preload := make(map[string][]string)
+	for _, name := range PreloadLibraryGUCs {
+		var setting string
+		if err := d.queryRow(ctx, "extensions", []any{&setting}, "SELECT COALESCE(current_setting($1, true), '')", name); err != nil {
+			return fmt.Errorf("failed to get %s: %w", name, err)
+		}
+		preload[name] = splitLibraryList(setting)
+	}
+
+	dumpData := ExtensionsDump{
+		Version:          "1.0.0",
+		Extensions:       extensions,
+		PreloadLibraries: preload,
+	}
+
+	jsonOutput, err := json.MarshalIndent(dumpData, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal extensions: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, ExtensionsJSONFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write extensions.json: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, CreateExtensionsSQLFile), []byte(generateCreateExtensionsSQL(dumpData)), 0644); err != nil {
+		return fmt.Errorf("failed to write create_extensions.sql: %w", err)
+	}
+	return nil
+}
+
+// splitLibraryList splits a preload library setting such as
+// `pg_hint_plan, "$libdir/auto_explain"` into its library names.
+func splitLibraryList(setting string) []string {
+	libs := []string{}
+	for _, lib := range strings.Split(setting, ",") {
+		lib = strings.Trim(strings.TrimSpace(lib), `"`)
+		if lib != "" {
+			libs = append(libs, lib)
+		}
+	}
+	return libs
+}
+
+// generateCreateExtensionsSQL writes a script that creates the extensions of
+// the source database, preferring the exported version. It only warns when an
+// extension or preloaded library is missing on the target, or when the
+// installed version differs, so that the rest of the replay can proceed.
+func generateCreateExtensionsSQL(exts ExtensionsDump) string {
+	var sb strings.Builder
+
+	for _, ext := range exts.Extensions {
+		sb.WriteString("DO $$\nBEGIN\n")
+		name, version := quoteLiteral(ext.Name), quoteLiteral(ext.Version)
+		sb.WriteString(fmt.Sprintf("    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = %s) THEN\n", name))
+		sb.WriteString(fmt.Sprintf("        RAISE WARNING 'extension \"%%\" is not available on the target', %s;\n", name))
+		sb.WriteString("        RETURN;\n")
+		sb.WriteString("    END IF;\n")
+		sb.WriteString(fmt.Sprintf("    CREATE SCHEMA IF NOT EXISTS %s;\n", quoteIdent(ext.Schema)))
+		sb.WriteString(fmt.Sprintf("    IF EXISTS (SELECT 1 FROM pg_available_extension_versions WHERE name = %s AND version = %s) THEN\n", name, version))
+		sb.WriteString(fmt.Sprintf("        CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s VERSION %s;\n", quoteIdent(ext.Name), quoteIdent(ext.Schema), version))
+		sb.WriteString("    ELSE\n")
+		sb.WriteString(fmt.Sprintf("        CREATE EXTENSION IF NOT EXISTS %s WITH SCHEMA %s;\n", quoteIdent(ext.Name), quoteIdent(ext.Schema)))
+		sb.WriteString("    END IF;\n")
+		sb.WriteString(fmt.Sprintf("    IF (SELECT extversion FROM pg_extension WHERE extname = %s) <> %s THEN\n", name, version))
+		sb.WriteString(fmt.Sprintf("        RAISE WARNING 'extension \"%%\" has version %% on the target, the dump was taken with %%', %s,\n", name))
+		sb.WriteString(fmt.Sprintf("            (SELECT extversion FROM pg_extension WHERE extname = %s), %s;\n", name, version))
+		sb.WriteString("    END IF;\n")
+		sb.WriteString("END $$;\n")
+	}
+
+	for _, name := range PreloadLibraryGUCs {
+		for _, lib := range exts.PreloadLibraries[name] {
+			sb.WriteString("DO $$\nBEGIN\n")
+			sb.WriteString(fmt.Sprintf("    IF NOT %s = ANY(string_to_array(replace(replace(COALESCE(current_setting(%s, true), ''), ' ', ''), '\"', ''), ',')) THEN\n", quoteLiteral(lib), quoteLiteral(name)))
+			sb.WriteString(fmt.Sprintf("        RAISE WARNING 'library \"%%\" is in %% on the source but not on the target', %s, %s;\n", quoteLiteral(lib), quoteLiteral(name)))
+			sb.WriteString("    END IF;\n")
+			sb.WriteString("END $$;\n")
+		}
+	}
+
+	return sb.String()
+}
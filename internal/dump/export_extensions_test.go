@@ -0,0 +1,75 @@
+package dump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLibraryList(t *testing.T) {
+	tests := map[string][]string{
+		"":                                     {},
+		"pg_hint_plan":                         {"pg_hint_plan"},
+		`pg_hint_plan, "$libdir/auto_explain"`: {"pg_hint_plan", "$libdir/auto_explain"},
+		` , "yb_pg_metrics",`:                  {"yb_pg_metrics"},
+	}
+	for in, want := range tests {
+		if got := splitLibraryList(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitLibraryList(%q) = %q, expected %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerateCreateExtensionsSQL(t *testing.T) {
+	sql := generateCreateExtensionsSQL(ExtensionsDump{
+		Extensions: []Extension{
+			{Name: "uuid-ossp", Version: "1.1", Schema: "public"},
+			{Name: "pg_trgm", Version: "1.6", Schema: "Ext Schema"},
+		},
+		PreloadLibraries: map[string][]string{
+			"shared_preload_libraries":  splitLibraryList(`pg_hint_plan, "$libdir/auto_explain"`),
+			"session_preload_libraries": {"o'lib"},
+		},
+	})
+	// One DO block per extension and per preloaded library.
+	assertStatements(t, "extensions", sql, 5)
+
+	for _, want := range []string{
+		// Missing extension warning.
+		"IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'uuid-ossp') THEN\n" +
+			"        RAISE WARNING 'extension \"%\" is not available on the target', 'uuid-ossp';\n" +
+			"        RETURN;",
+		// Exported version when available, any version otherwise.
+		"IF EXISTS (SELECT 1 FROM pg_available_extension_versions WHERE name = 'uuid-ossp' AND version = '1.1') THEN\n" +
+			"        CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\" WITH SCHEMA public VERSION '1.1';\n" +
+			"    ELSE\n" +
+			"        CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\" WITH SCHEMA public;",
+		"IF (SELECT extversion FROM pg_extension WHERE extname = 'uuid-ossp') <> '1.1' THEN",
+		`CREATE SCHEMA IF NOT EXISTS "Ext Schema";`,
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA "Ext Schema" VERSION '1.6';`,
+		// Preloaded libraries, with the quotes of the setting removed.
+		"IF NOT '$libdir/auto_explain' = ANY(string_to_array(replace(replace(COALESCE(current_setting('shared_preload_libraries', true), ''), ' ', ''), '\"', ''), ',')) THEN",
+		"RAISE WARNING 'library \"%\" is in % on the source but not on the target', 'pg_hint_plan', 'shared_preload_libraries';",
+		"'o''lib', 'session_preload_libraries';",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("expected %q in:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "local_preload_libraries") {
+		t.Errorf("unexpected check of an empty setting:\n%s", sql)
+	}
+}
+
+func TestGenerateCreateExtensionsSQLAdversarial(t *testing.T) {
+	for _, name := range adversarialNames {
+		sql := generateCreateExtensionsSQL(ExtensionsDump{
+			Extensions:       []Extension{{Name: name, Version: name, Schema: name}},
+			PreloadLibraries: map[string][]string{"shared_preload_libraries": {name}},
+		})
+		assertStatements(t, name, sql, 2)
+		if !strings.Contains(sql, "CREATE EXTENSION IF NOT EXISTS "+quoteIdent(name)+" WITH SCHEMA "+quoteIdent(name)+" VERSION "+quoteLiteral(name)+";") {
+			t.Errorf("%q: expected quoted names, got:\n%s", name, sql)
+		}
+	}
+}
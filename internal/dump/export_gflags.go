@@ -0,0 +1,306 @@
+package dump
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GFlagsFile = "gflags.json"
+	GFlagsDir  = "gflags"
+
+	GFlagsRoleMaster  = "master"
+	GFlagsRoleTServer = "tserver"
+
+	DefaultMasterWebPort  = 7000
+	DefaultTServerWebPort = 9000
+	DefaultWebTimeout     = 2 * time.Second
+)
+
+// GFlagsDump is the consolidated view written to gflags.json. Flags with the
+// same value on every node of a role are listed once under Master/TServer;
+// flags whose value differs between nodes are listed per node under
+// Differing[role][flag][endpoint].
+type GFlagsDump struct {
+	Version   string                                  `json:"version"`
+	Nodes     []GFlagsNode                            `json:"nodes"`
+	Master    map[string]string                       `json:"master"`
+	TServer   map[string]string                       `json:"tserver"`
+	Differing map[string]map[string]map[string]string `json:"differing"`
+}
+
+type GFlagsNode struct {
+	Role     string            `json:"role"`
+	Endpoint string            `json:"endpoint"`
+	File     string            `json:"file,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Flags    map[string]string `json:"-"`
+}
+
+func (d *Dumper) ExportGFlags(ctx context.Context) error {
+	client, err := d.webClient()
+	if err != nil {
+		return err
+	}
+
+	tservers := d.config.TServerWebEndpoints
+	if len(tservers) == 0 {
+		tservers = d.discoverTServerEndpoints(ctx)
+	}
+
+	var nodes []GFlagsNode
+	for _, endpoint := range tservers {
+		nodes = append(nodes, d.fetchNodeGFlags(ctx, client, GFlagsRoleTServer, endpoint))
+	}
+
+	masters := d.config.MasterWebEndpoints
+	if len(masters) == 0 {
+		masters = d.discoverMasterEndpoints(nodes)
+	}
+	for _, endpoint := range masters {
+		nodes = append(nodes, d.fetchNodeGFlags(ctx, client, GFlagsRoleMaster, endpoint))
+	}
+
+	fetched := 0
+	for _, node := range nodes {
+		if node.Error != "" {
+			d.logf("Warning: failed to fetch gflags from %s %s: %s\n", node.Role, node.Endpoint, node.Error)
+		} else {
+			fetched++
+		}
+	}
+	if fetched == 0 {
+		return fmt.Errorf("failed to fetch gflags from any node")
+	}
+
+	if err := os.MkdirAll(filepath.Join(d.config.OutputDir, GFlagsDir), 0755); err != nil {
+		return fmt.Errorf("failed to create gflags directory: %w", err)
+	}
+	for i := range nodes {
+		if nodes[i].Error != "" {
+			continue
+		}
+		nodes[i].File = filepath.Join(GFlagsDir, fmt.Sprintf("%s_%s.json", nodes[i].Role, sanitizeEndpoint(nodes[i].Endpoint)))
+		jsonOutput, err := json.MarshalIndent(nodes[i].Flags, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal gflags: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(d.config.OutputDir, nodes[i].File), jsonOutput, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", nodes[i].File, err)
+		}
+	}
+
+	consolidated := consolidateGFlags(nodes)
+	d.gflags = &consolidated
+
+	jsonOutput, err := json.MarshalIndent(consolidated, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal gflags: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, GFlagsFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write gflags.json: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Dumper) webClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: d.config.WebInsecureSkipVerify}
+	if d.config.WebCACert != "" {
+		pem, err := os.ReadFile(d.config.WebCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read web CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", d.config.WebCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return &http.Client{
+		Timeout:   d.config.WebTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// webURL turns an endpoint given as host:port or as a URL into the URL of path.
+func (d *Dumper) webURL(endpoint, path string) string {
+	if strings.Contains(endpoint, "://") {
+		return strings.TrimSuffix(endpoint, "/") + path
+	}
+	scheme := "http"
+	if d.config.WebTLS {
+		scheme = "https"
+	}
+	return scheme + "://" + endpoint + path
+}
+
+func (d *Dumper) webGet(ctx context.Context, client *http.Client, endpoint, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.webURL(endpoint, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.config.WebUser != "" {
+		req.SetBasicAuth(d.config.WebUser, d.config.WebPassword)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// discoverTServerEndpoints lists the tserver web endpoints of all nodes
+// reported by yb_servers(), falling back to the connection host.
+func (d *Dumper) discoverTServerEndpoints(ctx context.Context) []string {
+	fallback := []string{net.JoinHostPort(d.webHost(), fmt.Sprint(d.config.TServerWebPort))}
+
+	hosts, err := queryAll(ctx, d, "gflags", "SELECT host FROM yb_servers() ORDER BY host", nil, scanString)
+	if err != nil {
+		d.logf("Warning: failed to discover nodes with yb_servers(): %v\n", err)
+		return fallback
+	}
+	if len(hosts) == 0 {
+		return fallback
+	}
+
+	var endpoints []string
+	for _, host := range hosts {
+		endpoints = append(endpoints, net.JoinHostPort(host, fmt.Sprint(d.config.TServerWebPort)))
+	}
+	return endpoints
+}
+
+// discoverMasterEndpoints derives the master web endpoints from the
+// tserver_master_addrs flag of the first reachable tserver.
+func (d *Dumper) discoverMasterEndpoints(tservers []GFlagsNode) []string {
+	for _, node := range tservers {
+		addrs, ok := node.Flags["tserver_master_addrs"]
+		if !ok {
+			continue
+		}
+		var endpoints []string
+		for _, addr := range strings.Split(addrs, ",") {
+			host, _, err := net.SplitHostPort(strings.TrimSpace(addr))
+			if err != nil {
+				host = strings.TrimSpace(addr)
+			}
+			if host != "" {
+				endpoints = append(endpoints, net.JoinHostPort(host, fmt.Sprint(d.config.MasterWebPort)))
+			}
+		}
+		if len(endpoints) > 0 {
+			return endpoints
+		}
+	}
+	return []string{net.JoinHostPort(d.webHost(), fmt.Sprint(d.config.MasterWebPort))}
+}
+
+func (d *Dumper) fetchNodeGFlags(ctx context.Context, client *http.Client, role, endpoint string) GFlagsNode {
+	node := GFlagsNode{Role: role, Endpoint: endpoint}
+
+	body, err := d.webGet(ctx, client, endpoint, "/api/v1/varz")
+	if err != nil {
+		node.Error = err.Error()
+		return node
+	}
+
+	var data struct {
+		Flags []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+			Type  string `json:"type"`
+		} `json:"flags"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		node.Error = fmt.Sprintf("failed to parse gflags json: %v", err)
+		return node
+	}
+
+	node.Flags = make(map[string]string)
+	for _, f := range data.Flags {
+		if f.Type == "Default" && !d.config.GFlagsAll {
+			continue
+		}
+		node.Flags[f.Name] = f.Value
+	}
+	return node
+}
+
+// consolidateGFlags merges the flags of all reachable nodes per role.
+func consolidateGFlags(nodes []GFlagsNode) GFlagsDump {
+	dump := GFlagsDump{
+		Version:   "1.0.0",
+		Nodes:     nodes,
+		Master:    map[string]string{},
+		TServer:   map[string]string{},
+		Differing: map[string]map[string]map[string]string{},
+	}
+
+	for _, role := range []string{GFlagsRoleMaster, GFlagsRoleTServer} {
+		var roleNodes []GFlagsNode
+		names := map[string]bool{}
+		for _, node := range nodes {
+			if node.Role == role && node.Error == "" {
+				roleNodes = append(roleNodes, node)
+				for name := range node.Flags {
+					names[name] = true
+				}
+			}
+		}
+
+		common := dump.Master
+		if role == GFlagsRoleTServer {
+			common = dump.TServer
+		}
+
+		sortedNames := make([]string, 0, len(names))
+		for name := range names {
+			sortedNames = append(sortedNames, name)
+		}
+		sort.Strings(sortedNames)
+
+		for _, name := range sortedNames {
+			values := map[string]string{}
+			same := true
+			first, firstOk := roleNodes[0].Flags[name]
+			for _, node := range roleNodes {
+				value, ok := node.Flags[name]
+				if ok {
+					values[node.Endpoint] = value
+				}
+				if ok != firstOk || value != first {
+					same = false
+				}
+			}
+			if same {
+				common[name] = first
+				continue
+			}
+			if dump.Differing[role] == nil {
+				dump.Differing[role] = map[string]map[string]string{}
+			}
+			dump.Differing[role][name] = values
+		}
+	}
+	return dump
+}
+
+func sanitizeEndpoint(endpoint string) string {
+	return strings.NewReplacer("://", "_", ":", "_", "/", "_").Replace(endpoint)
+}
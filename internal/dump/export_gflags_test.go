@@ -0,0 +1,33 @@
+package dump
+
+import (
+	"testing"
+)
+
+func TestConsolidateGFlags(t *testing.T) {
+	nodes := []GFlagsNode{
+		{Role: GFlagsRoleTServer, Endpoint: "n1:9000", Flags: map[string]string{"ysql_pg_conf_csv": "work_mem=16MB", "placement_zone": "a"}},
+		{Role: GFlagsRoleTServer, Endpoint: "n2:9000", Flags: map[string]string{"ysql_pg_conf_csv": "work_mem=16MB", "placement_zone": "b", "only_n2": "x"}},
+		{Role: GFlagsRoleTServer, Endpoint: "n3:9000", Error: "connection refused"},
+		{Role: GFlagsRoleMaster, Endpoint: "n1:7000", Flags: map[string]string{"replication_factor": "3"}},
+	}
+
+	dump := consolidateGFlags(nodes)
+
+	if dump.TServer["ysql_pg_conf_csv"] != "work_mem=16MB" {
+		t.Errorf("expected common tserver flag, got %v", dump.TServer)
+	}
+	if dump.Master["replication_factor"] != "3" {
+		t.Errorf("expected common master flag, got %v", dump.Master)
+	}
+	zone := dump.Differing[GFlagsRoleTServer]["placement_zone"]
+	if zone["n1:9000"] != "a" || zone["n2:9000"] != "b" {
+		t.Errorf("expected placement_zone to differ per node, got %v", dump.Differing)
+	}
+	if _, ok := dump.Differing[GFlagsRoleTServer]["only_n2"]; !ok {
+		t.Errorf("expected flag missing on a node to be reported as differing, got %v", dump.Differing)
+	}
+	if _, ok := dump.TServer["placement_zone"]; ok {
+		t.Errorf("differing flag must not be listed as common")
+	}
+}
@@ -0,0 +1,294 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	OverriddenGUCsFile = "overridden_gucs.sql"
+	GUCSettingsFile    = "guc_settings.json"
+)
+
+// GUC levels in increasing order of precedence. A later level overrides an
+// earlier one, so overridden_gucs.sql is written in this order.
+const (
+	GUCLevelServer       = "server"
+	GUCLevelDatabase     = "database"
+	GUCLevelUser         = "user"
+	GUCLevelDatabaseUser = "database user"
+	GUCLevelSession      = "session"
+)
+
+var gucLevelOrder = []string{
+	GUCLevelServer,
+	GUCLevelDatabase,
+	GUCLevelUser,
+	GUCLevelDatabaseUser,
+	GUCLevelSession,
+}
+
+type GUCSetting struct {
+	Name    string `json:"name"`
+	Setting string `json:"setting"`
+	Source  string `json:"source"`
+	Level   string `json:"level"`
+}
+
+// DBRoleSetting is one pg_db_role_setting row. An empty Database or Role
+// means the entry applies to all databases or all roles respectively.
+type DBRoleSetting struct {
+	Database string   `json:"database,omitempty"`
+	Role     string   `json:"role,omitempty"`
+	Settings []string `json:"settings"`
+	Applied  bool     `json:"applied"`
+}
+
+type GUCSettingsDump struct {
+	Version        string          `json:"version"`
+	Database       string          `json:"database"`
+	SessionRole    string          `json:"session_role"`
+	AppRole        string          `json:"app_role"`
+	Settings       []GUCSetting    `json:"settings"`
+	DBRoleSettings []DBRoleSetting `json:"db_role_settings"`
+
+	// YSQLPgConfMismatches lists settings from the ysql_pg_conf_csv gflag
+	// that the capture session did not see, in YB mode only.
+	YSQLPgConfMismatches []GUCMismatch `json:"ysql_pg_conf_mismatches,omitempty"`
+}
+
+// ysqlPgConfSettings returns the settings of the ysql_pg_conf_csv tserver flag
+// captured by ExportGFlags, together with those whose value differs from what
+// the session sees in pg_settings.
+func (d *Dumper) ysqlPgConfSettings(ctx context.Context) ([]GUCSetting, []GUCMismatch, error) {
+	if d.gflags == nil {
+		return nil, nil, nil
+	}
+	csvValue, ok := d.gflags.TServerFlag(YSQLPgConfCSVFlag)
+	if !ok {
+		return nil, nil, nil
+	}
+	settings, err := parseYSQLPgConfCSV(csvValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(settings) == 0 {
+		return nil, nil, nil
+	}
+
+	names := make([]string, len(settings))
+	for i, s := range settings {
+		names[i] = s.Name
+	}
+	type sessionSetting struct{ name, setting, unit, vartype, source string }
+	sessionRows, err := queryAll(ctx, d, "gucs", `
+		SELECT name, setting, COALESCE(unit, ''), vartype, source FROM pg_settings WHERE name = ANY($1)`,
+		[]any{names}, func(rows pgx.Rows) (sessionSetting, error) {
+			var s sessionSetting
+			err := rows.Scan(&s.name, &s.setting, &s.unit, &s.vartype, &s.source)
+			return s, err
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query pg_settings: %w", err)
+	}
+	session := make(map[string]sessionSetting)
+	for _, s := range sessionRows {
+		session[s.name] = s
+	}
+
+	var mismatches []GUCMismatch
+	for _, s := range settings {
+		actual, ok := session[s.Name]
+		if !ok {
+			continue
+		}
+		if normalizeGUCValue(s.Setting, actual.unit, actual.vartype) != normalizeGUCValue(actual.setting, "", actual.vartype) {
+			mismatches = append(mismatches, GUCMismatch{
+				Name:          s.Name,
+				FlagValue:     s.Setting,
+				SessionValue:  actual.setting,
+				SessionSource: actual.source,
+			})
+		}
+	}
+	return settings, mismatches, nil
+}
+
+// gucSourceLevel maps a pg_settings.source value to the level it is replayed at.
+func gucSourceLevel(source string) string {
+	switch source {
+	case "database":
+		return GUCLevelDatabase
+	case "user":
+		return GUCLevelUser
+	case "database user":
+		return GUCLevelDatabaseUser
+	case "client", "interactive", "test", "session":
+		return GUCLevelSession
+	default:
+		return GUCLevelServer
+	}
+}
+
+func (d *Dumper) ExportOverriddenGUCs(ctx context.Context) error {
+	var database, sessionRole string
+	if err := d.queryRow(ctx, "gucs", []any{&database, &sessionRole}, "SELECT current_database(), current_user"); err != nil {
+		return fmt.Errorf("failed to get current database and role: %w", err)
+	}
+	appRole := d.config.AppRole
+	if appRole == "" {
+		appRole = sessionRole
+	}
+
+	// Cluster level settings from the ysql_pg_conf_csv gflag come first so
+	// that anything the server reports on top of them takes precedence.
+	settings, mismatches, err := d.ysqlPgConfSettings(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		d.logf("Warning: %s sets %s=%s but the session sees %s (source: %s)\n",
+			YSQLPgConfCSVFlag, m.Name, m.FlagValue, m.SessionValue, m.SessionSource)
+	}
+
+	serverSettings, err := queryAll(ctx, d, "gucs", `
+		SELECT name, setting, source FROM pg_settings
+		WHERE setting <> boot_val OR source NOT IN ('default', 'override')
+		ORDER BY name`, nil, func(rows pgx.Rows) (GUCSetting, error) {
+		var s GUCSetting
+		err := rows.Scan(&s.Name, &s.Setting, &s.Source)
+		return s, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pg_settings: %w", err)
+	}
+	for _, s := range serverSettings {
+		if !CBORelevantGUCParams[s.Name] {
+			continue
+		}
+		s.Level = gucSourceLevel(s.Source)
+		settings = append(settings, s)
+	}
+
+	dbRoleSettings, err := queryAll(ctx, d, "gucs", `
+		SELECT COALESCE(db.datname, ''), COALESCE(r.rolname, ''), s.setconfig
+		FROM pg_db_role_setting s
+			LEFT JOIN pg_database db ON db.oid = s.setdatabase
+			LEFT JOIN pg_roles r ON r.oid = s.setrole
+		WHERE s.setdatabase = 0 OR db.datname = current_database()
+		ORDER BY s.setdatabase, s.setrole`, nil, func(rows pgx.Rows) (DBRoleSetting, error) {
+		var s DBRoleSetting
+		err := rows.Scan(&s.Database, &s.Role, &s.Settings)
+		s.Applied = s.Role == "" || s.Role == appRole
+		return s, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pg_db_role_setting: %w", err)
+	}
+
+	dumpData := GUCSettingsDump{
+		Version:        "1.0.0",
+		Database:       database,
+		SessionRole:    sessionRole,
+		AppRole:        appRole,
+		Settings:       settings,
+		DBRoleSettings: dbRoleSettings,
+
+		YSQLPgConfMismatches: mismatches,
+	}
+	jsonOutput, err := json.MarshalIndent(dumpData, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal guc settings: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, GUCSettingsFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write guc_settings.json: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, OverriddenGUCsFile), []byte(generateGUCsSQL(dumpData)), 0644); err != nil {
+		return fmt.Errorf("failed to write overridden_gucs.sql: %w", err)
+	}
+	return nil
+}
+
+// generateGUCsSQL writes the effective settings for the application role as
+// SET statements ordered by precedence, so that replaying the file top to
+// bottom leaves every parameter at the value the application would see.
+//
+// pg_settings rows coming from database or role level settings describe the
+// capturing session, not the application role, so they are replaced by the
+// matching pg_db_role_setting entries.
+func generateGUCsSQL(gucs GUCSettingsDump) string {
+	byLevel := make(map[string]map[string]string)
+	set := func(level, name, value string) {
+		if !CBORelevantGUCParams[name] {
+			return
+		}
+		if byLevel[level] == nil {
+			byLevel[level] = make(map[string]string)
+		}
+		byLevel[level][name] = value
+	}
+
+	for _, s := range gucs.Settings {
+		switch s.Level {
+		case GUCLevelDatabase, GUCLevelUser, GUCLevelDatabaseUser:
+			continue
+		}
+		set(s.Level, s.Name, s.Setting)
+	}
+
+	for _, s := range gucs.DBRoleSettings {
+		if !s.Applied {
+			continue
+		}
+		level := GUCLevelDatabaseUser
+		if s.Role == "" {
+			level = GUCLevelDatabase
+		} else if s.Database == "" {
+			level = GUCLevelUser
+		}
+		for _, cfg := range s.Settings {
+			name, value, ok := strings.Cut(cfg, "=")
+			if !ok {
+				continue
+			}
+			set(level, strings.ToLower(name), value)
+		}
+	}
+
+	var sb strings.Builder
+	for _, level := range gucLevelOrder {
+		settings := byLevel[level]
+		if len(settings) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(settings))
+		for name := range settings {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		sb.WriteString(fmt.Sprintf("-- %s\n", level))
+		for _, name := range names {
+			sb.WriteString(fmt.Sprintf("SET %s=%s;\n", quoteGUCName(name), quoteLiteral(settings[name])))
+		}
+	}
+	return sb.String()
+}
+
+// quoteGUCName quotes each part of a setting name, which is qualified for
+// placeholder settings such as pg_hint_plan.enable_hint.
+func quoteGUCName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = quoteIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
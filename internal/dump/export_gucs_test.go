@@ -0,0 +1,38 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateGUCsSQLPrecedence(t *testing.T) {
+	gucs := GUCSettingsDump{
+		AppRole: "app",
+		Settings: []GUCSetting{
+			{Name: "random_page_cost", Setting: "1.1", Source: "configuration file", Level: GUCLevelServer},
+			// Set for the dumping role, must not leak into the application's settings.
+			{Name: "work_mem", Setting: "1024", Source: "user", Level: GUCLevelUser},
+			{Name: "enable_nestloop", Setting: "off", Source: "session", Level: GUCLevelSession},
+			{Name: "application_name", Setting: "psql", Source: "client", Level: GUCLevelSession},
+		},
+		DBRoleSettings: []DBRoleSetting{
+			{Database: "db", Settings: []string{"random_page_cost=2"}, Applied: true},
+			{Role: "app", Settings: []string{"work_mem=64MB"}, Applied: true},
+			{Role: "other", Settings: []string{"seq_page_cost=5"}, Applied: false},
+			{Database: "db", Role: "app", Settings: []string{"enable_nestloop=on"}, Applied: true},
+		},
+	}
+
+	sql := generateGUCsSQL(gucs)
+
+	expected := []string{
+		"-- server\nSET random_page_cost='1.1';\n",
+		"-- database\nSET random_page_cost='2';\n",
+		"-- user\nSET work_mem='64MB';\n",
+		"-- database user\nSET enable_nestloop='on';\n",
+		"-- session\nSET enable_nestloop='off';\n",
+	}
+	if sql != strings.Join(expected, "") {
+		t.Errorf("unexpected GUC SQL:\n%s", sql)
+	}
+}
@@ -0,0 +1,209 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	LocaleJSONFile        = "locale.json"
+	CreateDatabaseSQLFile = "create_database.sql"
+)
+
+// LocaleInfo describes the encoding and collation environment of the dumped
+// database. Histogram bounds of text columns are sorted by these collations,
+// so the replay database has to be created with the same settings.
+type LocaleInfo struct {
+	Version          string          `json:"version"`
+	ServerEncoding   string          `json:"server_encoding"`
+	Encoding         string          `json:"encoding"`
+	LcCollate        string          `json:"lc_collate"`
+	LcCtype          string          `json:"lc_ctype"`
+	LocaleProvider   string          `json:"locale_provider,omitempty"`
+	Locale           string          `json:"locale,omitempty"`
+	CollationVersion string          `json:"collation_version,omitempty"`
+	Collations       []CollationInfo `json:"collations"`
+}
+
+// CollationInfo is a collation referenced by the stacollN columns of the
+// exported statistics.
+type CollationInfo struct {
+	Nspname       string `json:"nspname"`
+	Collname      string `json:"collname"`
+	Provider      string `json:"provider"`
+	Collate       string `json:"collate,omitempty"`
+	Ctype         string `json:"ctype,omitempty"`
+	Version       string `json:"version,omitempty"`
+	ActualVersion string `json:"actual_version,omitempty"`
+}
+
+// localeProviders maps pg_database.datlocprovider and
+// pg_collation.collprovider codes to CREATE DATABASE provider names.
+var localeProviders = map[string]string{
+	"c": "libc",
+	"i": "icu",
+	"b": "builtin",
+	"d": "default",
+}
+
+func (d *Dumper) ExportLocale(ctx context.Context, relations []Relation) error {
+	pgVersion, err := d.serverVersionNum(ctx)
+	if err != nil {
+		return err
+	}
+
+	locale := LocaleInfo{Version: "1.0.0"}
+	var provider string
+	if err := d.queryRow(ctx, "locale", locale.databaseLocaleDest(&provider), databaseLocaleQuery(pgVersion)); err != nil {
+		return fmt.Errorf("failed to query database locale: %w", err)
+	}
+	locale.LocaleProvider = localeProviders[provider]
+
+	// stacollN exists from PG12 on.
+	if pgVersion >= 120000 {
+		schemasFilter := " AND n.nspname NOT IN ('pg_catalog', 'pg_toast', 'information_schema')"
+		relationNamesFilter, relationArgs := relationFilter(relations)
+		queryColl := fmt.Sprintf(`
+			SELECT DISTINCT cn.nspname, co.collname, co.collprovider::text,
+				COALESCE(co.collcollate, ''), COALESCE(co.collctype, ''),
+				COALESCE(co.collversion, ''), COALESCE(pg_collation_actual_version(co.oid), '')
+			FROM pg_statistic s
+				JOIN pg_class c ON c.oid = s.starelid
+				JOIN pg_namespace n ON c.relnamespace = n.oid
+				CROSS JOIN LATERAL unnest(ARRAY[s.stacoll1, s.stacoll2, s.stacoll3, s.stacoll4, s.stacoll5]) AS sc(colloid)
+				JOIN pg_collation co ON co.oid = sc.colloid
+				JOIN pg_namespace cn ON cn.oid = co.collnamespace
+			WHERE true %s %s
+			ORDER BY cn.nspname, co.collname
+		`, schemasFilter, relationNamesFilter)
+
+		locale.Collations, err = queryAll(ctx, d, "locale", queryColl, relationArgs, func(rows pgx.Rows) (CollationInfo, error) {
+			var coll CollationInfo
+			var provider string
+			err := rows.Scan(&coll.Nspname, &coll.Collname, &provider, &coll.Collate, &coll.Ctype, &coll.Version, &coll.ActualVersion)
+			coll.Provider = localeProviders[provider]
+			return coll, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to query statistics collations: %w", err)
+		}
+	}
+
+	jsonOutput, err := json.MarshalIndent(locale, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal locale: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, LocaleJSONFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write locale.json: %w", err)
+	}
+
+	createSQL := "-- Run with: psql -v dbname=<name> -f " + CreateDatabaseSQLFile + "\n" +
+		"CREATE DATABASE :\"dbname\" WITH " + locale.CreateDatabaseOptions() + ";\n"
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, CreateDatabaseSQLFile), []byte(createSQL), 0644); err != nil {
+		return fmt.Errorf("failed to write create_database.sql: %w", err)
+	}
+	return nil
+}
+
+// databaseLocaleQuery reads the encoding and collation settings of the
+// current database on a server of version pgVersion, in the order of
+// databaseLocaleDest. Servers before PG15 only have the libc provider.
+func databaseLocaleQuery(pgVersion int) string {
+	var providerColumns string
+	switch {
+	case pgVersion >= 170000:
+		providerColumns = "d.datlocprovider::text, COALESCE(d.datlocale, ''), COALESCE(d.datcollversion, '')"
+	case pgVersion >= 150000:
+		providerColumns = "d.datlocprovider::text, COALESCE(d.daticulocale, ''), COALESCE(d.datcollversion, '')"
+	default:
+		providerColumns = "'c', '', ''"
+	}
+	return fmt.Sprintf(`
+		SELECT current_setting('server_encoding'), pg_encoding_to_char(d.encoding), d.datcollate, d.datctype, %s
+		FROM pg_database d WHERE d.datname = current_database()`, providerColumns)
+}
+
+// databaseLocaleDest returns the scan destinations of databaseLocaleQuery.
+// The provider code is scanned into provider.
+func (l *LocaleInfo) databaseLocaleDest(provider *string) []any {
+	return []any{&l.ServerEncoding, &l.Encoding, &l.LcCollate, &l.LcCtype, provider, &l.Locale, &l.CollationVersion}
+}
+
+// QueryDatabaseLocale reads the encoding, collation and locale provider
+// settings of the database conn is connected to, for comparison with a
+// dumped LocaleInfo. Collations are not read.
+func QueryDatabaseLocale(ctx context.Context, conn *pgx.Conn) (*LocaleInfo, error) {
+	var pgVersion int
+	if err := conn.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&pgVersion); err != nil {
+		return nil, fmt.Errorf("failed to get server version: %w", err)
+	}
+	locale := &LocaleInfo{}
+	var provider string
+	if err := conn.QueryRow(ctx, databaseLocaleQuery(pgVersion)).Scan(locale.databaseLocaleDest(&provider)...); err != nil {
+		return nil, fmt.Errorf("failed to query database locale: %w", err)
+	}
+	locale.LocaleProvider = localeProviders[provider]
+	return locale, nil
+}
+
+// ReadLocaleInfo reads locale.json from a dump directory.
+func ReadLocaleInfo(dir string) (*LocaleInfo, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LocaleJSONFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locale.json: %w", err)
+	}
+	var locale LocaleInfo
+	if err := json.Unmarshal(data, &locale); err != nil {
+		return nil, fmt.Errorf("failed to parse locale.json: %w", err)
+	}
+	return &locale, nil
+}
+
+// CreateDatabaseOptions returns the CREATE DATABASE options, without the
+// leading WITH, that reproduce the locale. template0 is required because the
+// template database may have a different locale.
+func (l *LocaleInfo) CreateDatabaseOptions() string {
+	opts := []string{
+		"TEMPLATE template0",
+		"ENCODING " + quoteLiteral(l.Encoding),
+		"LC_COLLATE " + quoteLiteral(l.LcCollate),
+		"LC_CTYPE " + quoteLiteral(l.LcCtype),
+	}
+	switch l.LocaleProvider {
+	case "icu":
+		opts = append(opts, "LOCALE_PROVIDER icu", "ICU_LOCALE "+quoteLiteral(l.Locale))
+	case "builtin":
+		opts = append(opts, "LOCALE_PROVIDER builtin", "BUILTIN_LOCALE "+quoteLiteral(l.Locale))
+	}
+	return strings.Join(opts, " ")
+}
+
+// Mismatches lists the settings of other that differ from l. Only the
+// database level settings are compared. A provider missing from other is a
+// mismatch, so that an ICU or builtin database recreated with libc, or read
+// without its provider, does not pass. Dumps taken before the provider was
+// recorded only compare the libc settings.
+func (l *LocaleInfo) Mismatches(other *LocaleInfo) []string {
+	var diffs []string
+	check := func(name, want, got string) {
+		if want != got {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %q, got %q", name, want, got))
+		}
+	}
+	check("encoding", l.Encoding, other.Encoding)
+	check("lc_collate", l.LcCollate, other.LcCollate)
+	check("lc_ctype", l.LcCtype, other.LcCtype)
+	if l.LocaleProvider != "" {
+		check("locale_provider", l.LocaleProvider, other.LocaleProvider)
+		if l.LocaleProvider != "libc" {
+			check("locale", l.Locale, other.Locale)
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,123 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCreateDatabaseOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale LocaleInfo
+		want   string
+	}{
+		{
+			name:   "pre-PG15",
+			locale: LocaleInfo{Encoding: "UTF8", LcCollate: "en_US.UTF-8", LcCtype: "en_US.UTF-8"},
+			want:   "TEMPLATE template0 ENCODING 'UTF8' LC_COLLATE 'en_US.UTF-8' LC_CTYPE 'en_US.UTF-8'",
+		},
+		{
+			name:   "libc",
+			locale: LocaleInfo{Encoding: "UTF8", LcCollate: "C", LcCtype: "C", LocaleProvider: "libc"},
+			want:   "TEMPLATE template0 ENCODING 'UTF8' LC_COLLATE 'C' LC_CTYPE 'C'",
+		},
+		{
+			name:   "icu",
+			locale: LocaleInfo{Encoding: "UTF8", LcCollate: "en_US.UTF-8", LcCtype: "en_US.UTF-8", LocaleProvider: "icu", Locale: "und-u-ks-level2"},
+			want:   "TEMPLATE template0 ENCODING 'UTF8' LC_COLLATE 'en_US.UTF-8' LC_CTYPE 'en_US.UTF-8' LOCALE_PROVIDER icu ICU_LOCALE 'und-u-ks-level2'",
+		},
+		{
+			name:   "builtin",
+			locale: LocaleInfo{Encoding: "UTF8", LcCollate: "C", LcCtype: "C", LocaleProvider: "builtin", Locale: "C.UTF-8"},
+			want:   "TEMPLATE template0 ENCODING 'UTF8' LC_COLLATE 'C' LC_CTYPE 'C' LOCALE_PROVIDER builtin BUILTIN_LOCALE 'C.UTF-8'",
+		},
+		{
+			name:   "quoted",
+			locale: LocaleInfo{Encoding: "UTF8", LcCollate: "o'locale", LcCtype: "o'locale", LocaleProvider: "libc"},
+			want:   "TEMPLATE template0 ENCODING 'UTF8' LC_COLLATE 'o''locale' LC_CTYPE 'o''locale'",
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.locale.CreateDatabaseOptions(); got != tt.want {
+			t.Errorf("%s: CreateDatabaseOptions() = %s, expected %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleMismatches(t *testing.T) {
+	libc := LocaleInfo{Encoding: "UTF8", LcCollate: "en_US.UTF-8", LcCtype: "en_US.UTF-8", LocaleProvider: "libc"}
+	icu := LocaleInfo{Encoding: "UTF8", LcCollate: "en_US.UTF-8", LcCtype: "en_US.UTF-8", LocaleProvider: "icu", Locale: "en-US"}
+	builtin := LocaleInfo{Encoding: "UTF8", LcCollate: "C", LcCtype: "C", LocaleProvider: "builtin", Locale: "C.UTF-8"}
+	with := func(l LocaleInfo, change func(*LocaleInfo)) LocaleInfo {
+		change(&l)
+		return l
+	}
+
+	tests := []struct {
+		name         string
+		dumped, read LocaleInfo
+		want         []string
+	}{
+		{name: "libc", dumped: libc, read: libc},
+		{name: "icu", dumped: icu, read: icu},
+		{name: "builtin", dumped: builtin, read: builtin},
+		{
+			name:   "libc with a different collation",
+			dumped: libc,
+			read:   with(libc, func(l *LocaleInfo) { l.LcCollate = "C" }),
+			want:   []string{"lc_collate"},
+		},
+		{
+			name:   "libc ignores the provider locale",
+			dumped: libc,
+			read:   with(libc, func(l *LocaleInfo) { l.Locale = "en-US" }),
+		},
+		{
+			name:   "icu recreated with libc",
+			dumped: icu,
+			read:   with(icu, func(l *LocaleInfo) { l.LocaleProvider, l.Locale = "libc", "" }),
+			want:   []string{"locale_provider", "locale"},
+		},
+		{
+			name:   "icu read without its provider",
+			dumped: icu,
+			read:   with(icu, func(l *LocaleInfo) { l.LocaleProvider, l.Locale = "", "" }),
+			want:   []string{"locale_provider", "locale"},
+		},
+		{
+			name:   "icu with another locale",
+			dumped: icu,
+			read:   with(icu, func(l *LocaleInfo) { l.Locale = "de-DE" }),
+			want:   []string{"locale"},
+		},
+		{
+			name:   "builtin recreated with libc",
+			dumped: builtin,
+			read:   with(builtin, func(l *LocaleInfo) { l.LocaleProvider, l.Locale = "libc", "" }),
+			want:   []string{"locale_provider", "locale"},
+		},
+		{
+			name:   "dump without a provider",
+			dumped: with(libc, func(l *LocaleInfo) { l.LocaleProvider = "" }),
+			read:   icu,
+		},
+		{
+			name:   "encoding",
+			dumped: libc,
+			read:   with(libc, func(l *LocaleInfo) { l.Encoding = "LATIN1" }),
+			want:   []string{"encoding"},
+		},
+	}
+	for _, tt := range tests {
+		diffs := tt.dumped.Mismatches(&tt.read)
+		if len(diffs) != len(tt.want) {
+			t.Errorf("%s: expected mismatches of %v, got %q", tt.name, tt.want, diffs)
+			continue
+		}
+		for i, name := range tt.want {
+			if !strings.HasPrefix(diffs[i], name+":") {
+				t.Errorf("%s: expected a mismatch of %s, got %q", tt.name, name, diffs[i])
+			}
+		}
+	}
+}
@@ -7,38 +7,35 @@ import (
 	"path/filepath"
 )
 
-func (d *Dumper) ExportQueryPlan(queryPath string) error {
-	queryBytes, err := os.ReadFile(queryPath)
+// QueryPlanFile is the plan of the query file EXPLAINed by the dump.
+const QueryPlanFile = "query_plan.txt"
+
+func (d *Dumper) ExportQueryPlan(ctx context.Context, queryPath string) error {
+	query, err := d.config.readQuery(queryPath)
 	if err != nil {
-		return fmt.Errorf("failed to read query file: %w", err)
+		return err
 	}
-	query := string(queryBytes)
 
 	if d.config.EnableBaseScansCostModel && d.config.YBMode {
-		_, err := d.conn.Exec(context.Background(), "SET yb_enable_base_scans_cost_model=ON")
+		_, err := d.db.Exec(ctx, "SET yb_enable_base_scans_cost_model=ON")
 		if err != nil {
 			return fmt.Errorf("failed to set yb_enable_base_scans_cost_model: %w", err)
 		}
 	}
 
-	rows, err := d.conn.Query(context.Background(), "EXPLAIN "+query)
+	lines, err := queryAll(ctx, d, "query plan", "EXPLAIN "+query, nil, scanString)
 	if err != nil {
 		return fmt.Errorf("failed to execute explain: %w", err)
 	}
-	defer rows.Close()
 
-	outputPath := filepath.Join(d.config.OutputDir, "query_plan.txt")
+	outputPath := filepath.Join(d.config.OutputDir, QueryPlanFile)
 	f, err := os.Create(outputPath)
 	if err != nil {
 		return fmt.Errorf("failed to create query_plan.txt: %w", err)
 	}
 	defer f.Close()
 
-	for rows.Next() {
-		var line string
-		if err := rows.Scan(&line); err != nil {
-			return fmt.Errorf("failed to scan explain output: %w", err)
-		}
+	for _, line := range lines {
 		if _, err := f.WriteString(line + "\n"); err != nil {
 			return fmt.Errorf("failed to write to query_plan.txt: %w", err)
 		}
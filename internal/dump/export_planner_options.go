@@ -0,0 +1,253 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	PlannerOptionsJSONFile      = "planner_options.json"
+	ImportPlannerOptionsSQLFile = "import_planner_options.sql"
+)
+
+// TablespacePlannerOptions are the spcoptions consulted by the planner when
+// costing scans of relations stored in a tablespace.
+var TablespacePlannerOptions = map[string]bool{
+	"seq_page_cost":              true,
+	"random_page_cost":           true,
+	"effective_io_concurrency":   true,
+	"maintenance_io_concurrency": true,
+}
+
+type PlannerOptionsDump struct {
+	Version     string              `json:"version"`
+	Tablespaces []TablespaceOptions `json:"tablespaces"`
+	Relations   []RelationOptions   `json:"relations"`
+	Columns     []ColumnOptions     `json:"columns"`
+}
+
+// TablespaceOptions holds the options of a tablespace. Placeholder is the psql
+// variable the import script uses in place of the tablespace name, so that it
+// can be mapped to a different tablespace on the target.
+type TablespaceOptions struct {
+	Name        string            `json:"name"`
+	Placeholder string            `json:"placeholder"`
+	Options     map[string]string `json:"options"`
+}
+
+type RelationOptions struct {
+	Nspname    string            `json:"nspname"`
+	Relname    string            `json:"relname"`
+	Relkind    string            `json:"relkind"`
+	Tablespace string            `json:"tablespace,omitempty"`
+	Options    map[string]string `json:"options,omitempty"`
+}
+
+// ColumnOptions holds per-column attoptions and attstattarget. A statistics
+// target of -1 means the column uses default_statistics_target.
+type ColumnOptions struct {
+	Nspname          string            `json:"nspname"`
+	Relname          string            `json:"relname"`
+	Attname          string            `json:"attname"`
+	StatisticsTarget int32             `json:"attstattarget"`
+	Options          map[string]string `json:"options,omitempty"`
+}
+
+func (d *Dumper) ExportPlannerOptions(ctx context.Context, relations []Relation) error {
+	schemasFilter := " AND n.nspname NOT IN ('pg_catalog', 'pg_toast', 'information_schema')"
+	relationNamesFilter, relationArgs := relationFilter(relations)
+
+	// 1. Relation options and tablespaces
+	queryRel := fmt.Sprintf(`
+		SELECT n.nspname, c.relname, c.relkind::text, COALESCE(ts.spcname, ''), COALESCE(c.reloptions, '{}')
+		FROM pg_class c
+			JOIN pg_namespace n ON c.relnamespace = n.oid
+			LEFT JOIN pg_tablespace ts ON ts.oid = c.reltablespace
+		WHERE c.relkind IN ('r', 'p', 'm', 'i', 'I') %s %s
+			AND (c.reloptions IS NOT NULL OR c.reltablespace <> 0)
+		ORDER BY n.nspname, c.relname
+	`, schemasFilter, relationNamesFilter)
+
+	relOptions, err := queryAll(ctx, d, "planner options", queryRel, relationArgs, func(rows pgx.Rows) (RelationOptions, error) {
+		var rel RelationOptions
+		var options []string
+		err := rows.Scan(&rel.Nspname, &rel.Relname, &rel.Relkind, &rel.Tablespace, &options)
+		rel.Options = parseOptions(options)
+		return rel, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query relation options: %w", err)
+	}
+	usedTablespaces := []string{}
+	for _, rel := range relOptions {
+		if rel.Tablespace != "" {
+			usedTablespaces = append(usedTablespaces, rel.Tablespace)
+		}
+	}
+
+	// 2. Column options and statistics targets. attstattarget is nullable
+	// from PG17 on, where NULL replaces -1 as "use the default".
+	queryCol := fmt.Sprintf(`
+		SELECT n.nspname, c.relname, a.attname, COALESCE(a.attstattarget::int4, -1), COALESCE(a.attoptions, '{}')
+		FROM pg_attribute a
+			JOIN pg_class c ON c.oid = a.attrelid
+			JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE a.attnum > 0 AND NOT a.attisdropped
+			AND c.relkind IN ('r', 'p', 'm') %s %s
+			AND (a.attoptions IS NOT NULL OR COALESCE(a.attstattarget::int4, -1) >= 0)
+		ORDER BY n.nspname, c.relname, a.attnum
+	`, schemasFilter, relationNamesFilter)
+
+	columns, err := queryAll(ctx, d, "planner options", queryCol, relationArgs, func(rows pgx.Rows) (ColumnOptions, error) {
+		var col ColumnOptions
+		var options []string
+		err := rows.Scan(&col.Nspname, &col.Relname, &col.Attname, &col.StatisticsTarget, &options)
+		col.Options = parseOptions(options)
+		return col, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query column options: %w", err)
+	}
+
+	// 3. Tablespaces holding the exported relations, plus any tablespace
+	// (including pg_default) with planner options set.
+	tablespaces, err := queryAll(ctx, d, "planner options", `
+		SELECT spcname, COALESCE(spcoptions, '{}')
+		FROM pg_tablespace
+		WHERE spcname <> 'pg_global' AND (spcoptions IS NOT NULL OR spcname = ANY($1))
+		ORDER BY spcname
+	`, []any{usedTablespaces}, func(rows pgx.Rows) (TablespaceOptions, error) {
+		var ts TablespaceOptions
+		var options []string
+		err := rows.Scan(&ts.Name, &options)
+		ts.Options = parseOptions(options)
+		return ts, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pg_tablespace: %w", err)
+	}
+	for i := range tablespaces {
+		tablespaces[i].Placeholder = fmt.Sprintf("tblspc_%d", i+1)
+	}
+
+	dumpData := PlannerOptionsDump{
+		Version:     "1.0.0",
+		Tablespaces: tablespaces,
+		Relations:   relOptions,
+		Columns:     columns,
+	}
+
+	jsonOutput, err := json.MarshalIndent(dumpData, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal planner options: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, PlannerOptionsJSONFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write planner_options.json: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, ImportPlannerOptionsSQLFile), []byte(generatePlannerOptionsSQL(dumpData)), 0644); err != nil {
+		return fmt.Errorf("failed to write import_planner_options.sql: %w", err)
+	}
+	return nil
+}
+
+// parseOptions turns a reloptions style "key=value" array into a map.
+func parseOptions(options []string) map[string]string {
+	if len(options) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(options))
+	for _, opt := range options {
+		key, value, _ := strings.Cut(opt, "=")
+		m[key] = value
+	}
+	return m
+}
+
+func formatOptions(options map[string]string, keep func(string) bool) string {
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		if keep == nil || keep(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s = %s", quoteIdent(k), quoteLiteral(options[k])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func alterRelationCommand(relkind string) string {
+	switch relkind {
+	case "i", "I":
+		return "ALTER INDEX"
+	case "m":
+		return "ALTER MATERIALIZED VIEW"
+	default:
+		return "ALTER TABLE"
+	}
+}
+
+// generatePlannerOptionsSQL writes a psql script restoring the planner
+// options. Tablespaces are referenced through psql variables and only touched
+// when a tablespace of that name exists on the target; otherwise a warning is
+// printed and the relations stay in the default tablespace.
+func generatePlannerOptionsSQL(opts PlannerOptionsDump) string {
+	var sb strings.Builder
+
+	if len(opts.Tablespaces) > 0 {
+		sb.WriteString("-- Tablespaces. Edit the values below to map them to tablespaces existing on the target.\n")
+		for _, ts := range opts.Tablespaces {
+			sb.WriteString(fmt.Sprintf("\\set %s %s\n", ts.Placeholder, psqlQuote(ts.Name)))
+		}
+	}
+
+	for _, ts := range opts.Tablespaces {
+		sb.WriteString(fmt.Sprintf("\nSELECT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = :'%s') AS %s_exists \\gset\n", ts.Placeholder, ts.Placeholder))
+		sb.WriteString(fmt.Sprintf("\\if :%s_exists\n", ts.Placeholder))
+		if options := formatOptions(ts.Options, func(k string) bool { return TablespacePlannerOptions[k] }); options != "" {
+			sb.WriteString(fmt.Sprintf("ALTER TABLESPACE :\"%s\" SET (%s);\n", ts.Placeholder, options))
+		}
+		for _, rel := range opts.Relations {
+			if rel.Tablespace == ts.Name {
+				sb.WriteString(fmt.Sprintf("%s %s SET TABLESPACE :\"%s\";\n", alterRelationCommand(rel.Relkind), quoteQualified(rel.Nspname, rel.Relname), ts.Placeholder))
+			}
+		}
+		sb.WriteString("\\else\n")
+		sb.WriteString(fmt.Sprintf("\\echo 'WARNING: tablespace' :'%s' 'does not exist on the target, its planner options are not applied'\n", ts.Placeholder))
+		sb.WriteString("\\endif\n")
+	}
+
+	if len(opts.Relations) > 0 {
+		sb.WriteString("\n-- Relation options\n")
+	}
+	for _, rel := range opts.Relations {
+		if options := formatOptions(rel.Options, nil); options != "" {
+			sb.WriteString(fmt.Sprintf("%s %s SET (%s);\n", alterRelationCommand(rel.Relkind), quoteQualified(rel.Nspname, rel.Relname), options))
+		}
+	}
+
+	if len(opts.Columns) > 0 {
+		sb.WriteString("\n-- Column options\n")
+	}
+	for _, col := range opts.Columns {
+		if options := formatOptions(col.Options, nil); options != "" {
+			sb.WriteString(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET (%s);\n", quoteQualified(col.Nspname, col.Relname), quoteIdent(col.Attname), options))
+		}
+		if col.StatisticsTarget >= 0 {
+			sb.WriteString(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET STATISTICS %d;\n", quoteQualified(col.Nspname, col.Relname), quoteIdent(col.Attname), col.StatisticsTarget))
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,44 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePlannerOptionsSQL(t *testing.T) {
+	opts := PlannerOptionsDump{
+		Tablespaces: []TablespaceOptions{
+			{Name: "fast", Placeholder: "tblspc_1", Options: map[string]string{"random_page_cost": "1.1", "replica_placement": "{}"}},
+		},
+		Relations: []RelationOptions{
+			{Nspname: "public", Relname: "t", Relkind: "r", Tablespace: "fast", Options: map[string]string{"parallel_workers": "4"}},
+			{Nspname: "public", Relname: "t_idx", Relkind: "i", Options: map[string]string{"fillfactor": "80"}},
+		},
+		Columns: []ColumnOptions{
+			{Nspname: "public", Relname: "t", Attname: "a", StatisticsTarget: 500, Options: map[string]string{"n_distinct": "-0.5"}},
+			{Nspname: "public", Relname: "t", Attname: "b", StatisticsTarget: -1, Options: map[string]string{"n_distinct_inherited": "10"}},
+		},
+	}
+
+	sql := generatePlannerOptionsSQL(opts)
+
+	expected := []string{
+		"\\set tblspc_1 'fast'\n",
+		"\\if :tblspc_1_exists\nALTER TABLESPACE :\"tblspc_1\" SET (random_page_cost = '1.1');\nALTER TABLE public.t SET TABLESPACE :\"tblspc_1\";\n\\else\n",
+		"ALTER TABLE public.t SET (parallel_workers = '4');\n",
+		"ALTER INDEX public.t_idx SET (fillfactor = '80');\n",
+		"ALTER TABLE public.t ALTER COLUMN a SET (n_distinct = '-0.5');\nALTER TABLE public.t ALTER COLUMN a SET STATISTICS 500;\n",
+		"ALTER TABLE public.t ALTER COLUMN b SET (n_distinct_inherited = '10');\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(sql, e) {
+			t.Errorf("expected %q in:\n%s", e, sql)
+		}
+	}
+	if strings.Contains(sql, "replica_placement") {
+		t.Errorf("non planner tablespace option leaked into:\n%s", sql)
+	}
+	if strings.Contains(sql, "COLUMN b SET STATISTICS") {
+		t.Errorf("default statistics target should not be restored:\n%s", sql)
+	}
+}
@@ -34,8 +34,8 @@ func TestGetPgStatisticInsertQuery(t *testing.T) {
 		t.Errorf("expected correct relname, got: %s", query)
 	}
 	// Check array format
-	// stanumbers1: '{0.500000,0.500000}'::real[]
-	if !strings.Contains(query, "'{0.500000,0.500000}'::real[]") {
+	// stanumbers1: '{0.5,0.5}'::real[]
+	if !strings.Contains(query, "'{0.5,0.5}'::real[]") {
 		t.Errorf("expected correct stanumbers1, got: %s", query)
 	}
 	// stavalues1: array_in('{"10", "20"}', 'pg_catalog.int4'::regtype, -1)::anyarray
@@ -45,7 +45,7 @@ func TestGetPgStatisticInsertQuery(t *testing.T) {
 	}
 }
 
-func TestGenerateImportSQL(t *testing.T) {
+func TestYBCatalogRenderer(t *testing.T) {
 	pgClass := []PgClassStats{
 		{
 			Relname:       "users",
@@ -57,15 +57,15 @@ func TestGenerateImportSQL(t *testing.T) {
 	}
 	pgStat := []PgStatisticStats{}
 
-	sql, err := generateImportSQL(true, 14, pgClass, pgStat)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
+	sql := renderRows(t, RenderYBCatalog, StatisticsInfo{PGMajorVersion: 14}, pgClass, pgStat)
 
-	if !strings.Contains(sql, "UPDATE pg_class SET reltuples = 1000.000000") {
+	if !strings.Contains(sql, "UPDATE pg_class SET reltuples = 1000,") {
 		t.Errorf("expected UPDATE pg_class, got: %s", sql)
 	}
 	if !strings.Contains(sql, "SET yb_non_ddl_txn_for_sys_tables_allowed = ON") {
 		t.Errorf("expected YB specific GUC, got: %s", sql)
 	}
+	if !strings.Contains(sql, "WHERE db_oid = (SELECT oid FROM pg_database WHERE datname = current_database())") {
+		t.Errorf("expected catalog version bump of the current database, got: %s", sql)
+	}
 }
@@ -0,0 +1,313 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	TablePropertiesJSONFile = "table_properties.json"
+)
+
+// YBTableLayout records how YugabyteDB distributes the exported relations.
+// With yb_enable_base_scans_cost_model the planner costs scans by the number
+// of tablets and the sharding scheme, so the replay recreates them.
+type YBTableLayout struct {
+	Version           string              `json:"version"`
+	DatabaseColocated bool                `json:"database_colocated"`
+	Relations         []YBTableProperties `json:"relations"`
+}
+
+type YBTableProperties struct {
+	Nspname           string   `json:"nspname"`
+	Relname           string   `json:"relname"`
+	Relkind           string   `json:"relkind"`
+	NumTablets        int64    `json:"num_tablets"`
+	NumHashKeyColumns int64    `json:"num_hash_key_columns"`
+	HashKeyColumns    []string `json:"hash_key_columns,omitempty"`
+	IsColocated       bool     `json:"is_colocated"`
+	Tablegroup        string   `json:"tablegroup,omitempty"`
+	ColocationID      uint32   `json:"colocation_id,omitempty"`
+}
+
+func (d *Dumper) ExportYBTableProperties(ctx context.Context, relations []Relation) error {
+	layout := YBTableLayout{Version: "1.0.0"}
+
+	if err := d.queryRow(ctx, "table properties", []any{&layout.DatabaseColocated}, "SELECT yb_is_database_colocated()"); err != nil {
+		return fmt.Errorf("failed to query database colocation: %w", err)
+	}
+
+	schemasFilter := " AND n.nspname NOT IN ('pg_catalog', 'pg_toast', 'information_schema')"
+	relationNamesFilter, relationArgs := relationFilter(relations)
+	query := fmt.Sprintf(`
+		SELECT n.nspname, c.relname, c.relkind::text, p.num_tablets, p.num_hash_key_columns, p.is_colocated,
+			COALESCE(tg.grpname, ''), COALESCE(p.colocation_id, 0),
+			ARRAY(SELECT a.attname
+				FROM pg_index i
+					CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, ord)
+					JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+				WHERE ((c.relkind = 'i' AND i.indexrelid = c.oid) OR (c.relkind <> 'i' AND i.indrelid = c.oid AND i.indisprimary))
+					AND k.ord <= p.num_hash_key_columns
+				ORDER BY k.ord)
+		FROM pg_class c
+			JOIN pg_namespace n ON c.relnamespace = n.oid
+			CROSS JOIN LATERAL yb_table_properties(c.oid) p
+			LEFT JOIN pg_yb_tablegroup tg ON tg.oid = p.tablegroup_oid
+		WHERE c.relkind IN ('r', 'm', 'i') %s %s
+		ORDER BY n.nspname, c.relname
+	`, schemasFilter, relationNamesFilter)
+
+	var err error
+	layout.Relations, err = queryAll(ctx, d, "table properties", query, relationArgs, func(rows pgx.Rows) (YBTableProperties, error) {
+		var p YBTableProperties
+		err := rows.Scan(&p.Nspname, &p.Relname, &p.Relkind, &p.NumTablets, &p.NumHashKeyColumns, &p.IsColocated,
+			&p.Tablegroup, &p.ColocationID, &p.HashKeyColumns)
+		return p, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query yb_table_properties: %w", err)
+	}
+
+	jsonOutput, err := json.MarshalIndent(layout, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal table properties: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, TablePropertiesJSONFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write table_properties.json: %w", err)
+	}
+
+	d.ybLayout = &layout
+	return nil
+}
+
+// ddlStatement is a statement of pg_dump output, kept as its lines.
+type ddlStatement struct {
+	lines []string
+}
+
+func (s *ddlStatement) text() string {
+	return strings.Join(s.lines, "\n")
+}
+
+// splitDDLStatements groups pg_dump output lines into statements ending with
+// a line terminated by a semicolon. Statements are only inspected, never
+// reparsed, so a function body split in the middle is joined back unchanged.
+func splitDDLStatements(ddl string) []*ddlStatement {
+	var stmts []*ddlStatement
+	current := &ddlStatement{}
+	for _, line := range strings.Split(strings.TrimRight(ddl, "\n"), "\n") {
+		current.lines = append(current.lines, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") || strings.HasPrefix(line, "\\") {
+			stmts = append(stmts, current)
+			current = &ddlStatement{}
+		}
+	}
+	if len(current.lines) > 0 {
+		stmts = append(stmts, current)
+	}
+	return stmts
+}
+
+func joinDDLStatements(stmts []*ddlStatement) string {
+	var sb strings.Builder
+	for _, stmt := range stmts {
+		if len(stmt.lines) == 0 {
+			continue
+		}
+		sb.WriteString(stmt.text())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// parseQualifiedName splits a possibly quoted, possibly schema qualified
+// name such as public."Order Items" at the start of s. It returns the schema,
+// the name and the rest of s.
+func parseQualifiedName(s string) (string, string, string) {
+	var parts []string
+	var sb strings.Builder
+	inQuotes := false
+	i := 0
+loop:
+	for ; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case inQuotes && ch == '"' && i+1 < len(s) && s[i+1] == '"':
+			sb.WriteByte('"')
+			i++
+		case ch == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && ch == '.':
+			parts = append(parts, sb.String())
+			sb.Reset()
+		case !inQuotes && (ch == ' ' || ch == '(' || ch == '\n' || ch == ';'):
+			break loop
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	parts = append(parts, sb.String())
+	if len(parts) == 1 {
+		return "", parts[0], s[i:]
+	}
+	return parts[len(parts)-2], parts[len(parts)-1], s[i:]
+}
+
+var ddlStatementKinds = []struct {
+	prefix string
+	kind   string
+}{
+	{"CREATE TABLE ", "TABLE"},
+	{"CREATE UNLOGGED TABLE ", "TABLE"},
+	{"CREATE INDEX ", "INDEX"},
+	{"CREATE UNIQUE INDEX ", "INDEX"},
+	{"ALTER TABLE ONLY ", "ALTER TABLE"},
+	{"ALTER TABLE ", "ALTER TABLE"},
+}
+
+// statementTarget returns the kind (TABLE, INDEX or ALTER TABLE) of a
+// statement and the relation it operates on. For CREATE INDEX this is the
+// index, in the schema of its table.
+func statementTarget(stmt string) (kind, schema, name string) {
+	s := strings.TrimSpace(stmt)
+	for _, k := range ddlStatementKinds {
+		if !strings.HasPrefix(s, k.prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(s, k.prefix), "IF NOT EXISTS ")
+		schema, name, rest = parseQualifiedName(rest)
+		if k.kind == "INDEX" {
+			if _, after, ok := strings.Cut(rest, " ON "); ok {
+				schema, _, _ = parseQualifiedName(strings.TrimPrefix(after, "ONLY "))
+			}
+		}
+		return k.kind, schema, name
+	}
+	return "", "", ""
+}
+
+// addClause adds a YugabyteDB storage clause to a CREATE TABLE or CREATE
+// INDEX statement. Clauses go before the WHERE of a partial index, and
+// colocation is merged into an existing WITH list.
+func addClause(stmt *ddlStatement, clause string) {
+	text := strings.TrimSuffix(strings.TrimRight(stmt.text(), " "), ";")
+	if opt, ok := strings.CutPrefix(clause, "WITH ("); ok && strings.Contains(text, "\nWITH (") {
+		text = strings.Replace(text, "\nWITH (", "\nWITH ("+strings.TrimSuffix(opt, ")")+", ", 1)
+	} else if idx := strings.LastIndex(text, " WHERE "); idx >= 0 {
+		text = text[:idx] + " " + clause + text[idx:]
+	} else {
+		text += " " + clause
+	}
+	stmt.lines = strings.Split(text+";", "\n")
+}
+
+// foldPrimaryKeys moves ALTER TABLE ... ADD CONSTRAINT ... PRIMARY KEY
+// statements of pg_dump output into the matching CREATE TABLE, as
+// YugabyteDB shards a table by the primary key it is created with.
+func foldPrimaryKeys(stmts []*ddlStatement) []*ddlStatement {
+	creates := make(map[string]*ddlStatement)
+	for _, stmt := range stmts {
+		if kind, schema, name := statementTarget(stmt.text()); kind == "TABLE" {
+			creates[schema+"."+name] = stmt
+		}
+	}
+	for _, stmt := range stmts {
+		kind, schema, name := statementTarget(stmt.text())
+		if kind != "ALTER TABLE" {
+			continue
+		}
+		text := strings.TrimSpace(stmt.text())
+		idx := strings.Index(text, "ADD CONSTRAINT ")
+		if idx < 0 || !strings.Contains(text, " PRIMARY KEY ") {
+			continue
+		}
+		create, ok := creates[schema+"."+name]
+		if !ok {
+			continue
+		}
+		constraint := strings.TrimSuffix(text[idx+len("ADD "):], ";")
+		for i := len(create.lines) - 1; i > 0; i-- {
+			if strings.HasPrefix(create.lines[i], ")") {
+				create.lines[i-1] += ","
+				create.lines = append(create.lines[:i], append([]string{"    " + constraint}, create.lines[i:]...)...)
+				stmt.lines = nil
+				break
+			}
+		}
+	}
+	return stmts
+}
+
+// applyYBTableLayout rewrites pg_dump output so that tables and indexes are
+// recreated with the tablet count, sharding and colocation of the source.
+// Primary keys are folded into CREATE TABLE, as SPLIT INTO applies to the
+// primary key. It returns the rewritten DDL and warnings about layouts that
+// cannot be reproduced.
+func applyYBTableLayout(ddl string, layout *YBTableLayout) (string, []string) {
+	props := make(map[string]YBTableProperties)
+	for _, p := range layout.Relations {
+		props[p.Nspname+"."+p.Relname] = p
+	}
+
+	stmts := foldPrimaryKeys(splitDDLStatements(ddl))
+	var warnings []string
+
+	tablegroups := make(map[string]bool)
+	for _, stmt := range stmts {
+		if len(stmt.lines) == 0 {
+			continue
+		}
+		kind, schema, name := statementTarget(stmt.text())
+		if kind == "" || kind == "ALTER TABLE" {
+			continue
+		}
+		p, ok := props[schema+"."+name]
+		if !ok {
+			continue
+		}
+
+		isTable := kind == "TABLE"
+		if isTable && p.Tablegroup != "" {
+			tablegroups[p.Tablegroup] = true
+			addClause(stmt, "TABLEGROUP "+quoteIdent(p.Tablegroup))
+			continue
+		}
+		if p.IsColocated {
+			continue
+		}
+		if isTable && layout.DatabaseColocated {
+			addClause(stmt, "WITH (colocation = false)")
+		}
+		if p.NumHashKeyColumns > 0 {
+			addClause(stmt, fmt.Sprintf("SPLIT INTO %d TABLETS", p.NumTablets))
+		} else if p.NumTablets > 1 {
+			warnings = append(warnings, fmt.Sprintf("%s.%s is range sharded into %d tablets; split points are not reproduced", p.Nspname, p.Relname, p.NumTablets))
+		}
+	}
+
+	var preamble []string
+	expected := "false"
+	if layout.DatabaseColocated {
+		expected = "true"
+	}
+	preamble = append(preamble,
+		fmt.Sprintf("SELECT yb_is_database_colocated() <> %s AS cbo_colocation_differs \\gset", expected),
+		"\\if :cbo_colocation_differs",
+		fmt.Sprintf("\\echo 'WARNING: the target database colocation differs from the source (colocated = %s)'", expected),
+		"\\endif",
+	)
+	for _, p := range layout.Relations {
+		if p.Tablegroup != "" && tablegroups[p.Tablegroup] {
+			preamble = append(preamble, fmt.Sprintf("CREATE TABLEGROUP %s;", quoteIdent(p.Tablegroup)))
+			delete(tablegroups, p.Tablegroup)
+		}
+	}
+
+	return strings.Join(preamble, "\n") + "\n" + joinDDLStatements(stmts), warnings
+}
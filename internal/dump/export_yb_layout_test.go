@@ -0,0 +1,78 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPgDumpDDL = `CREATE TABLE public.orders (
+    id integer NOT NULL,
+    note text
+);
+CREATE TABLE public."Line Items" (
+    id integer NOT NULL
+)
+WITH (fillfactor='80');
+CREATE TABLE public.ranged (
+    k integer NOT NULL
+);
+ALTER TABLE ONLY public.orders
+    ADD CONSTRAINT orders_pkey PRIMARY KEY ((id) HASH);
+CREATE INDEX orders_note_idx ON public.orders USING lsm (note HASH) WHERE (note IS NOT NULL);
+`
+
+func TestApplyYBTableLayout(t *testing.T) {
+	layout := &YBTableLayout{
+		DatabaseColocated: true,
+		Relations: []YBTableProperties{
+			{Nspname: "public", Relname: "orders", Relkind: "r", NumTablets: 3, NumHashKeyColumns: 1},
+			{Nspname: "public", Relname: "orders_note_idx", Relkind: "i", NumTablets: 2, NumHashKeyColumns: 1},
+			{Nspname: "public", Relname: "Line Items", Relkind: "r", NumTablets: 1, IsColocated: true},
+			{Nspname: "public", Relname: "ranged", Relkind: "r", NumTablets: 4},
+		},
+	}
+
+	ddl, warnings := applyYBTableLayout(testPgDumpDDL, layout)
+
+	expected := []string{
+		"SELECT yb_is_database_colocated() <> true AS cbo_colocation_differs \\gset\n",
+		"    note text,\n    CONSTRAINT orders_pkey PRIMARY KEY ((id) HASH)\n) WITH (colocation = false) SPLIT INTO 3 TABLETS;\n",
+		"WITH (fillfactor='80');\n",
+		"USING lsm (note HASH) SPLIT INTO 2 TABLETS WHERE (note IS NOT NULL);\n",
+		"CREATE TABLE public.ranged (\n    k integer NOT NULL\n) WITH (colocation = false);\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(ddl, e) {
+			t.Errorf("expected %q in:\n%s", e, ddl)
+		}
+	}
+	if strings.Contains(ddl, "ALTER TABLE ONLY public.orders") {
+		t.Errorf("primary key should be folded into CREATE TABLE:\n%s", ddl)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "public.ranged") {
+		t.Errorf("expected a warning about the range sharded table, got %v", warnings)
+	}
+}
+
+func TestApplyYBTableLayoutTablegroup(t *testing.T) {
+	ddl, _ := applyYBTableLayout(testPgDumpDDL, &YBTableLayout{
+		Relations: []YBTableProperties{
+			{Nspname: "public", Relname: "ranged", Relkind: "r", NumTablets: 1, Tablegroup: "Sales TG"},
+		},
+	})
+	for _, e := range []string{
+		`CREATE TABLEGROUP "Sales TG";`,
+		"CREATE TABLE public.ranged (\n    k integer NOT NULL\n) TABLEGROUP \"Sales TG\";\n",
+	} {
+		if !strings.Contains(ddl, e) {
+			t.Errorf("expected %q in:\n%s", e, ddl)
+		}
+	}
+}
+
+func TestParseQualifiedName(t *testing.T) {
+	schema, name, rest := parseQualifiedName(`"My Schema"."Order ""Items""" (`)
+	if schema != "My Schema" || name != `Order "Items"` || rest != " (" {
+		t.Errorf("unexpected result %q %q %q", schema, name, rest)
+	}
+}
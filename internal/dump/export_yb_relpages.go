@@ -0,0 +1,267 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	RelpagesJSONFile      = "relpages.json"
+	ImportRelpagesSQLFile = "import_relpages.sql"
+
+	RelpagesSourceRaw   = "raw"
+	RelpagesSourceDocDB = "docdb"
+
+	relpagesFromTablets     = "tablets"
+	relpagesFromPgTableSize = "pg_table_size"
+)
+
+// YBRelpagesDump records, beside the raw pg_class.relpages, a relpages
+// equivalent estimated from the size of the relation in DocDB. relpages is
+// mostly 0 on YugabyteDB, while a PostgreSQL simulator running with
+// enable_cbo_statistics_simulation trusts it.
+type YBRelpagesDump struct {
+	Version   string          `json:"version"`
+	BlockSize int64           `json:"block_size"`
+	Relations []YBRelpagesRow `json:"relations"`
+}
+
+type YBRelpagesRow struct {
+	Nspname           string `json:"nspname"`
+	Relname           string `json:"relname"`
+	Relkind           string `json:"relkind"`
+	Relpages          int32  `json:"relpages"`
+	SSTSizeBytes      int64  `json:"sst_size_bytes"`
+	WALSizeBytes      int64  `json:"wal_size_bytes"`
+	EstimatedRelpages int64  `json:"estimated_relpages"`
+	Source            string `json:"source,omitempty"`
+
+	tableID string
+	oid     uint32
+}
+
+// tabletSize is the on-disk size of one tablet as reported by a tserver.
+type tabletSize struct {
+	tableID string
+	sst     int64
+	wal     int64
+}
+
+func (d *Dumper) ExportYBRelpages(ctx context.Context, relations []Relation) error {
+	var blockSize int64
+	var dbOid uint32
+	if err := d.queryRow(ctx, "relpages", []any{&blockSize, &dbOid},
+		"SELECT current_setting('block_size')::int8, oid FROM pg_database WHERE datname = current_database()"); err != nil {
+		return fmt.Errorf("failed to query block size: %w", err)
+	}
+
+	schemasFilter := " AND n.nspname NOT IN ('pg_catalog', 'pg_toast', 'information_schema')"
+	relationNamesFilter, relationArgs := relationFilter(relations)
+	rows, err := queryAll(ctx, d, "relpages", fmt.Sprintf(`
+		SELECT n.nspname, c.relname, c.relkind::text, c.relpages, c.relfilenode::int8, c.oid
+		FROM pg_class c JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE c.relkind IN ('r', 'm', 'i') %s %s
+		ORDER BY n.nspname, c.relname
+	`, schemasFilter, relationNamesFilter), relationArgs, func(rows pgx.Rows) (YBRelpagesRow, error) {
+		var r YBRelpagesRow
+		var relfilenode int64
+		err := rows.Scan(&r.Nspname, &r.Relname, &r.Relkind, &r.Relpages, &relfilenode, &r.oid)
+		r.tableID = ybTableID(dbOid, uint32(relfilenode))
+		return r, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query relations: %w", err)
+	}
+
+	colocated := make(map[string]bool)
+	if d.ybLayout != nil {
+		for _, p := range d.ybLayout.Relations {
+			colocated[p.Nspname+"."+p.Relname] = p.IsColocated || p.Tablegroup != ""
+		}
+	}
+
+	tablets, err := d.fetchTabletSizes(ctx)
+	if err != nil {
+		d.logf("Warning: failed to fetch tablet sizes, falling back to pg_table_size(): %v\n", err)
+	}
+	sizes := sumTabletSizes(tablets)
+
+	// Colocated relations share their tablet, so its size is not theirs.
+	// They and the relations missing from the tablets fall back to
+	// pg_table_size(), fetched in one query.
+	var fallback []uint32
+	for i := range rows {
+		r := &rows[i]
+		if size, ok := sizes[r.tableID]; ok && !colocated[r.Nspname+"."+r.Relname] {
+			r.SSTSizeBytes, r.WALSizeBytes, r.Source = size.sst, size.wal, relpagesFromTablets
+		} else {
+			fallback = append(fallback, r.oid)
+		}
+	}
+	tableSizes := make(map[uint32]int64)
+	if len(fallback) > 0 {
+		type tableSize struct {
+			oid  uint32
+			size *int64
+		}
+		found, err := queryAll(ctx, d, "relpages", "SELECT c.oid, pg_table_size(c.oid) FROM pg_class c WHERE c.oid = ANY($1::oid[])",
+			[]any{fallback}, func(rows pgx.Rows) (tableSize, error) {
+				var t tableSize
+				err := rows.Scan(&t.oid, &t.size)
+				return t, err
+			})
+		if err != nil {
+			d.logf("Warning: failed to query pg_table_size(): %v\n", err)
+		}
+		for _, t := range found {
+			if t.size != nil {
+				tableSizes[t.oid] = *t.size
+			}
+		}
+	}
+
+	for i := range rows {
+		r := &rows[i]
+		if r.Source == "" {
+			size, ok := tableSizes[r.oid]
+			if !ok {
+				continue
+			}
+			r.SSTSizeBytes, r.Source = size, relpagesFromPgTableSize
+		}
+		r.EstimatedRelpages = estimateRelpages(r.SSTSizeBytes+r.WALSizeBytes, blockSize)
+	}
+
+	dumpData := YBRelpagesDump{
+		Version:   "1.0.0",
+		BlockSize: blockSize,
+		Relations: rows,
+	}
+	jsonOutput, err := json.MarshalIndent(dumpData, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal relpages: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, RelpagesJSONFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write relpages.json: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, ImportRelpagesSQLFile), []byte(generateRelpagesSQL(dumpData)), 0644); err != nil {
+		return fmt.Errorf("failed to write import_relpages.sql: %w", err)
+	}
+	return nil
+}
+
+// ybTableID returns the DocDB table id of a YSQL relation, which encodes the
+// database oid and the relfilenode.
+func ybTableID(dbOid, relfilenode uint32) string {
+	return fmt.Sprintf("%08x0000300080000000%08x", dbOid, relfilenode)
+}
+
+// estimateRelpages converts a size in bytes into blocks, rounding up so that
+// a non empty relation has at least one page. The result fits relpages.
+func estimateRelpages(sizeBytes, blockSize int64) int64 {
+	if sizeBytes <= 0 || blockSize <= 0 {
+		return 0
+	}
+	return min((sizeBytes+blockSize-1)/blockSize, math.MaxInt32)
+}
+
+// fetchTabletSizes reads the tablets hosted by every tserver. Replicas of a
+// tablet are reported by each tserver holding one; they are deduplicated by
+// sumTabletSizes.
+func (d *Dumper) fetchTabletSizes(ctx context.Context) (map[string]tabletSize, error) {
+	client, err := d.webClient()
+	if err != nil {
+		return nil, err
+	}
+	tservers := d.config.TServerWebEndpoints
+	if len(tservers) == 0 {
+		tservers = d.discoverTServerEndpoints(ctx)
+	}
+
+	tablets := make(map[string]tabletSize)
+	var errs []string
+	for _, endpoint := range tservers {
+		body, err := d.webGet(ctx, client, endpoint, "/api/v1/tablets")
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", endpoint, err))
+			continue
+		}
+		if err := parseTabletSizes(body, tablets); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", endpoint, err))
+		}
+	}
+	if len(tablets) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return tablets, nil
+}
+
+// parseTabletSizes adds the tablets of a tserver /api/v1/tablets response to
+// tablets, keeping the largest replica of each tablet.
+func parseTabletSizes(body []byte, tablets map[string]tabletSize) error {
+	var data struct {
+		Tablets []struct {
+			TableID    string `json:"table_uuid"`
+			TabletID   string `json:"tablet_id"`
+			OnDiskSize struct {
+				SSTFilesSizeBytes int64 `json:"sst_files_size_bytes"`
+				WALFilesSizeBytes int64 `json:"wal_files_size_bytes"`
+			} `json:"on_disk_size"`
+		} `json:"tablets"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("failed to parse tablets json: %w", err)
+	}
+	for _, t := range data.Tablets {
+		size := tabletSize{tableID: t.TableID, sst: t.OnDiskSize.SSTFilesSizeBytes, wal: t.OnDiskSize.WALFilesSizeBytes}
+		if prev, ok := tablets[t.TabletID]; !ok || size.sst+size.wal > prev.sst+prev.wal {
+			tablets[t.TabletID] = size
+		}
+	}
+	return nil
+}
+
+// sumTabletSizes sums the tablet sizes per table id.
+func sumTabletSizes(tablets map[string]tabletSize) map[string]tabletSize {
+	sizes := make(map[string]tabletSize)
+	for _, t := range tablets {
+		s := sizes[t.tableID]
+		s.tableID = t.tableID
+		s.sst += t.sst
+		s.wal += t.wal
+		sizes[t.tableID] = s
+	}
+	return sizes
+}
+
+// generateRelpagesSQL writes UPDATE statements replacing relpages with the
+// DocDB estimate. It is meant to run after import_statistics.sql when the
+// estimate is preferred over the raw value, on PostgreSQL or YugabyteDB.
+func generateRelpagesSQL(relpages YBRelpagesDump) string {
+	var sb strings.Builder
+	sb.WriteString("DO $$ BEGIN\n")
+	sb.WriteString("    IF EXISTS (SELECT 1 FROM pg_settings WHERE name = 'yb_non_ddl_txn_for_sys_tables_allowed') THEN\n")
+	sb.WriteString("        PERFORM set_config('yb_non_ddl_txn_for_sys_tables_allowed', 'on', false);\n")
+	sb.WriteString("    END IF;\n")
+	sb.WriteString("END $$;\n\n")
+	for _, r := range relpages.Relations {
+		if r.Source == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("UPDATE pg_class SET relpages = %d WHERE relnamespace = %s::regnamespace AND relname = %s;\n",
+			r.EstimatedRelpages, quoteLiteral(quoteIdent(r.Nspname)), quoteLiteral(r.Relname)))
+	}
+	sb.WriteString("\nDO $$ BEGIN\n")
+	sb.WriteString("    IF to_regclass('pg_catalog.pg_yb_catalog_version') IS NOT NULL THEN\n")
+	sb.WriteString(fmt.Sprintf("        EXECUTE %s;\n", quoteLiteral(strings.TrimSuffix(strings.TrimSpace(ybCatalogVersionBumpSQL), ";"))))
+	sb.WriteString("    END IF;\n")
+	sb.WriteString("END $$;\n")
+	return sb.String()
+}
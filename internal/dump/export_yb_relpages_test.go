@@ -0,0 +1,74 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYBTableID(t *testing.T) {
+	if id := ybTableID(13281, 16384); id != "000033e1000030008000000000004000" {
+		t.Errorf("unexpected table id %s", id)
+	}
+}
+
+func TestEstimateRelpages(t *testing.T) {
+	tests := []struct {
+		size, expected int64
+	}{
+		{0, 0},
+		{1, 1},
+		{8192, 1},
+		{8193, 2},
+		{1 << 50, 1<<31 - 1},
+	}
+	for _, tt := range tests {
+		if got := estimateRelpages(tt.size, 8192); got != tt.expected {
+			t.Errorf("estimateRelpages(%d) = %d, expected %d", tt.size, got, tt.expected)
+		}
+	}
+}
+
+func TestParseTabletSizes(t *testing.T) {
+	leader := `{"tablets": [
+		{"table_uuid": "t1", "tablet_id": "a", "on_disk_size": {"sst_files_size_bytes": 1000, "wal_files_size_bytes": 200}},
+		{"table_uuid": "t1", "tablet_id": "b", "on_disk_size": {"sst_files_size_bytes": 3000, "wal_files_size_bytes": 0}}
+	]}`
+	follower := `{"tablets": [
+		{"table_uuid": "t1", "tablet_id": "a", "on_disk_size": {"sst_files_size_bytes": 900, "wal_files_size_bytes": 200}}
+	]}`
+
+	tablets := make(map[string]tabletSize)
+	for _, body := range []string{follower, leader} {
+		if err := parseTabletSizes([]byte(body), tablets); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	size := sumTabletSizes(tablets)["t1"]
+	if size.sst != 4000 || size.wal != 200 {
+		t.Errorf("expected replicas to be counted once, got sst %d wal %d", size.sst, size.wal)
+	}
+}
+
+func TestGenerateRelpagesSQL(t *testing.T) {
+	sql := generateRelpagesSQL(YBRelpagesDump{
+		BlockSize: 8192,
+		Relations: []YBRelpagesRow{
+			{Nspname: "public", Relname: "orders", EstimatedRelpages: 42, Source: relpagesFromTablets},
+			{Nspname: "public", Relname: "unknown"},
+		},
+	})
+	if !strings.Contains(sql, "UPDATE pg_class SET relpages = 42 WHERE relnamespace = 'public'::regnamespace AND relname = 'orders';") {
+		t.Errorf("expected relpages update, got:\n%s", sql)
+	}
+	if strings.Contains(sql, "'unknown'") {
+		t.Errorf("relations without an estimate should be skipped, got:\n%s", sql)
+	}
+	bump := quoteLiteral(strings.TrimSuffix(strings.TrimSpace(ybCatalogVersionBumpSQL), ";"))
+	if !strings.Contains(sql, "EXECUTE "+bump+";") {
+		t.Errorf("expected the quoted catalog version bump, got:\n%s", sql)
+	}
+	if len(splitSQLStatements(sql)) != 3 {
+		t.Errorf("expected the script to split into 3 statements, got %q", splitSQLStatements(sql))
+	}
+}
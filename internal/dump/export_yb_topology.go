@@ -0,0 +1,226 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	TopologyJSONFile      = "yb_topology.json"
+	ImportTopologySQLFile = "import_topology.sql"
+)
+
+// YBTopology records the cluster nodes and the data placement consulted by
+// yb_enable_geolocation_costing.
+type YBTopology struct {
+	Version       string                `json:"version"`
+	ConnectedNode YBPlacement           `json:"connected_node"`
+	Servers       []YBServer            `json:"servers"`
+	Tablespaces   []YBTablespace        `json:"tablespaces"`
+	Relations     []YBRelationPlacement `json:"relations"`
+}
+
+type YBPlacement struct {
+	Cloud  string `json:"cloud"`
+	Region string `json:"region"`
+	Zone   string `json:"zone"`
+}
+
+type YBServer struct {
+	Host     string `json:"host"`
+	Port     int64  `json:"port"`
+	NodeType string `json:"node_type"`
+	PublicIP string `json:"public_ip,omitempty"`
+	YBPlacement
+}
+
+type YBTablespace struct {
+	Name             string            `json:"name"`
+	ReplicaPlacement *ReplicaPlacement `json:"replica_placement,omitempty"`
+}
+
+type ReplicaPlacement struct {
+	NumReplicas     int              `json:"num_replicas"`
+	PlacementBlocks []PlacementBlock `json:"placement_blocks"`
+}
+
+type PlacementBlock struct {
+	Cloud            string `json:"cloud"`
+	Region           string `json:"region"`
+	Zone             string `json:"zone"`
+	MinNumReplicas   int    `json:"min_num_replicas"`
+	LeaderPreference int    `json:"leader_preference,omitempty"`
+}
+
+type YBRelationPlacement struct {
+	Nspname    string `json:"nspname"`
+	Relname    string `json:"relname"`
+	Relkind    string `json:"relkind"`
+	Tablespace string `json:"tablespace"`
+}
+
+func (d *Dumper) ExportYBTopology(ctx context.Context, relations []Relation) error {
+	topology := YBTopology{Version: "1.0.0"}
+
+	if err := d.queryRow(ctx, "topology", []any{&topology.ConnectedNode.Cloud, &topology.ConnectedNode.Region, &topology.ConnectedNode.Zone},
+		"SELECT yb_server_cloud(), yb_server_region(), yb_server_zone()"); err != nil {
+		return fmt.Errorf("failed to query placement of the connected node: %w", err)
+	}
+
+	var err error
+	topology.Servers, err = queryAll(ctx, d, "topology", `
+		SELECT host, port, node_type, cloud, region, zone, COALESCE(public_ip, '')
+		FROM yb_servers() ORDER BY host`, nil, func(rows pgx.Rows) (YBServer, error) {
+		var s YBServer
+		err := rows.Scan(&s.Host, &s.Port, &s.NodeType, &s.Cloud, &s.Region, &s.Zone, &s.PublicIP)
+		return s, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query yb_servers(): %w", err)
+	}
+
+	type tablespaceRow struct {
+		name    string
+		options []string
+	}
+	tablespaces, err := queryAll(ctx, d, "topology", `
+		SELECT spcname, COALESCE(spcoptions, '{}') FROM pg_tablespace
+		WHERE spcname NOT IN ('pg_default', 'pg_global') ORDER BY spcname`, nil, func(rows pgx.Rows) (tablespaceRow, error) {
+		var ts tablespaceRow
+		err := rows.Scan(&ts.name, &ts.options)
+		return ts, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query pg_tablespace: %w", err)
+	}
+	for _, row := range tablespaces {
+		ts := YBTablespace{Name: row.name}
+		if placement, ok := parseOptions(row.options)["replica_placement"]; ok {
+			ts.ReplicaPlacement = &ReplicaPlacement{}
+			if err := json.Unmarshal([]byte(placement), ts.ReplicaPlacement); err != nil {
+				return fmt.Errorf("failed to parse replica_placement of tablespace %s: %w", ts.Name, err)
+			}
+		}
+		topology.Tablespaces = append(topology.Tablespaces, ts)
+	}
+
+	schemasFilter := " AND n.nspname NOT IN ('pg_catalog', 'pg_toast', 'information_schema')"
+	relationNamesFilter, relationArgs := relationFilter(relations)
+	topology.Relations, err = queryAll(ctx, d, "topology", fmt.Sprintf(`
+		SELECT n.nspname, c.relname, c.relkind::text, ts.spcname
+		FROM pg_class c
+			JOIN pg_namespace n ON c.relnamespace = n.oid
+			JOIN pg_tablespace ts ON ts.oid = c.reltablespace
+		WHERE c.relkind IN ('r', 'p', 'm', 'i', 'I') %s %s
+		ORDER BY n.nspname, c.relname
+	`, schemasFilter, relationNamesFilter), relationArgs, func(rows pgx.Rows) (YBRelationPlacement, error) {
+		var r YBRelationPlacement
+		err := rows.Scan(&r.Nspname, &r.Relname, &r.Relkind, &r.Tablespace)
+		return r, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query relation tablespaces: %w", err)
+	}
+
+	jsonOutput, err := json.MarshalIndent(topology, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal topology: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, TopologyJSONFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write yb_topology.json: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, ImportTopologySQLFile), []byte(generateTopologySQL(topology)), 0644); err != nil {
+		return fmt.Errorf("failed to write import_topology.sql: %w", err)
+	}
+	return nil
+}
+
+// leaderBlocks returns the placement blocks preferred for leaders, or all
+// blocks when no leader preference is set.
+func (p *ReplicaPlacement) leaderBlocks() []PlacementBlock {
+	best := 0
+	for _, b := range p.PlacementBlocks {
+		if b.LeaderPreference > 0 && (best == 0 || b.LeaderPreference < best) {
+			best = b.LeaderPreference
+		}
+	}
+	if best == 0 {
+		return p.PlacementBlocks
+	}
+	var blocks []PlacementBlock
+	for _, b := range p.PlacementBlocks {
+		if b.LeaderPreference == best {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// localToZone reports whether the leaders of the placement are in the zone
+// of node. Geolocation costing then treats the tablespace as local, which a
+// single node cluster can emulate by placing the tablespace on its own node.
+func (p *ReplicaPlacement) localToZone(node YBPlacement) bool {
+	blocks := p.leaderBlocks()
+	if len(blocks) == 0 {
+		return false
+	}
+	for _, b := range blocks {
+		if b.Cloud != node.Cloud || b.Region != node.Region || (b.Zone != "*" && b.Zone != node.Zone) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateTopologySQL writes a psql script creating the tablespaces of the
+// source. A tablespace is created with its original placement when the target
+// has nodes in every placement block. Otherwise a tablespace local to the
+// capture node is emulated on the target's own node, and any other placement
+// is reported as not reproducible.
+func generateTopologySQL(topology YBTopology) string {
+	var sb strings.Builder
+
+	for i, ts := range topology.Tablespaces {
+		if ts.ReplicaPlacement == nil {
+			continue
+		}
+		v := fmt.Sprintf("yb_ts_%d", i+1)
+		placement, _ := json.Marshal(ts.ReplicaPlacement)
+
+		var blocks []string
+		for _, b := range ts.ReplicaPlacement.PlacementBlocks {
+			blocks = append(blocks, fmt.Sprintf("(%s, %s, %s)", quoteLiteral(b.Cloud), quoteLiteral(b.Region), quoteLiteral(b.Zone)))
+		}
+
+		sb.WriteString(fmt.Sprintf("\n-- Tablespace %s\n", ts.Name))
+		sb.WriteString(fmt.Sprintf("SELECT NOT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = %s) AS %s_missing,\n", quoteLiteral(ts.Name), v))
+		sb.WriteString(fmt.Sprintf("    NOT EXISTS (SELECT 1 FROM (VALUES %s) AS b(cloud, region, zone)\n", strings.Join(blocks, ", ")))
+		sb.WriteString("        WHERE NOT EXISTS (SELECT 1 FROM yb_servers() s\n")
+		sb.WriteString("            WHERE s.cloud = b.cloud AND s.region = b.region AND (b.zone = '*' OR s.zone = b.zone))")
+		sb.WriteString(fmt.Sprintf(") AS %s_reproducible,\n", v))
+		sb.WriteString("    json_build_object('num_replicas', 1, 'placement_blocks', json_build_array(json_build_object(\n")
+		sb.WriteString("        'cloud', yb_server_cloud(), 'region', yb_server_region(), 'zone', yb_server_zone(), 'min_num_replicas', 1)))::text")
+		sb.WriteString(fmt.Sprintf(" AS %s_local \\gset\n", v))
+		sb.WriteString(fmt.Sprintf("\\if :%s_missing\n", v))
+		sb.WriteString(fmt.Sprintf("\\if :%s_reproducible\n", v))
+		sb.WriteString(fmt.Sprintf("CREATE TABLESPACE %s WITH (replica_placement = %s);\n", quoteIdent(ts.Name), quoteLiteral(string(placement))))
+		sb.WriteString("\\else\n")
+		if ts.ReplicaPlacement.localToZone(topology.ConnectedNode) {
+			sb.WriteString("\\echo " + psqlQuote(fmt.Sprintf("NOTICE: tablespace %s is emulated on the local node, its placement is local to the capture node", ts.Name)) + "\n")
+			sb.WriteString(fmt.Sprintf("CREATE TABLESPACE %s WITH (replica_placement = :'%s_local');\n", quoteIdent(ts.Name), v))
+		} else {
+			sb.WriteString("\\echo " + psqlQuote(fmt.Sprintf("WARNING: placement of tablespace %s cannot be reproduced on the target: %s", ts.Name, placement)) + "\n")
+		}
+		sb.WriteString("\\endif\n")
+		sb.WriteString("\\endif\n")
+	}
+
+	return sb.String()
+}
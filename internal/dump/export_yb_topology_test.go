@@ -0,0 +1,34 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateTopologySQL(t *testing.T) {
+	topology := YBTopology{
+		ConnectedNode: YBPlacement{Cloud: "aws", Region: "us-east-1", Zone: "us-east-1a"},
+		Tablespaces: []YBTablespace{
+			{Name: "local_ts", ReplicaPlacement: &ReplicaPlacement{NumReplicas: 1, PlacementBlocks: []PlacementBlock{
+				{Cloud: "aws", Region: "us-east-1", Zone: "us-east-1a", MinNumReplicas: 1},
+			}}},
+			{Name: "remote_ts", ReplicaPlacement: &ReplicaPlacement{NumReplicas: 2, PlacementBlocks: []PlacementBlock{
+				{Cloud: "aws", Region: "us-east-1", Zone: "us-east-1a", MinNumReplicas: 1, LeaderPreference: 2},
+				{Cloud: "aws", Region: "eu-west-1", Zone: "eu-west-1a", MinNumReplicas: 1, LeaderPreference: 1},
+			}}},
+			{Name: "plain_ts"},
+		},
+	}
+
+	sql := generateTopologySQL(topology)
+
+	if !strings.Contains(sql, "CREATE TABLESPACE local_ts WITH (replica_placement = :'yb_ts_1_local');") {
+		t.Errorf("expected local tablespace to be emulated:\n%s", sql)
+	}
+	if !strings.Contains(sql, "WARNING: placement of tablespace remote_ts cannot be reproduced") {
+		t.Errorf("expected remote tablespace to be reported:\n%s", sql)
+	}
+	if strings.Contains(sql, "plain_ts") {
+		t.Errorf("tablespace without placement should be skipped:\n%s", sql)
+	}
+}
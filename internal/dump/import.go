@@ -0,0 +1,108 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DefaultImportBatchSize is the number of statements run per transaction by
+// ImportSQLFile.
+const DefaultImportBatchSize = 100
+
+// sqlStatement is a statement of a SQL script. meta is set when it is, or is
+// terminated by, a psql meta-command, which is then held by command.
+type sqlStatement struct {
+	text    string
+	meta    bool
+	command string
+}
+
+// splitSQLScript splits a script into statements at semicolons outside of
+// quotes, dollar quotes and comments. A psql meta-command runs to the end of
+// its line and, like psql, ends the statement it follows, as in
+// "SELECT ... \\gset". Comment-only fragments are dropped.
+func splitSQLScript(script string) []sqlStatement {
+	var stmts []sqlStatement
+	var sb strings.Builder
+	flush := func(command string) {
+		if stmt := strings.TrimSpace(sb.String()); stmt != "" && !isCommentOnly(stmt) {
+			stmts = append(stmts, sqlStatement{text: stmt, meta: command != "", command: command})
+		}
+		sb.Reset()
+	}
+
+	for i := 0; i < len(script); {
+		kind, n := nextSQLToken(script[i:])
+		token := script[i : i+n]
+		i += n
+		sb.WriteString(token)
+		switch kind {
+		case sqlMeta:
+			flush(strings.TrimSpace(token))
+		case sqlSemicolon:
+			flush("")
+		}
+	}
+	flush("")
+	return stmts
+}
+
+// splitSQLStatements returns the statements of splitSQLScript as text.
+func splitSQLStatements(script string) []string {
+	var texts []string
+	for _, stmt := range splitSQLScript(script) {
+		texts = append(texts, stmt.text)
+	}
+	return texts
+}
+
+// ImportSQLFile runs a plain SQL script such as import_statistics.sql on
+// conn. Statements run in transactions of batchSize statements, and a batch
+// failing with a transient error is rolled back and retried as a whole under
+// the given phase. psql meta-commands are not supported.
+func ImportSQLFile(ctx context.Context, conn *pgx.Conn, path string, batchSize int, retrier *Retrier, phase string) error {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var stmts []string
+	for _, stmt := range splitSQLScript(string(script)) {
+		if stmt.meta {
+			return fmt.Errorf("%s contains psql meta-command in %q", path, firstLine(stmt.text))
+		}
+		stmts = append(stmts, stmt.text)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+
+	for start := 0; start < len(stmts); start += batchSize {
+		batch := stmts[start:min(start+batchSize, len(stmts))]
+		err := retrier.Do(ctx, phase, func() error {
+			tx, err := conn.Begin(ctx)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback(ctx)
+			for _, stmt := range batch {
+				if _, err := tx.Exec(ctx, stmt); err != nil {
+					return fmt.Errorf("failed to execute %q: %w", firstLine(stmt), err)
+				}
+			}
+			return tx.Commit(ctx)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
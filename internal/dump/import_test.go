@@ -0,0 +1,35 @@
+package dump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSQLStatements(t *testing.T) {
+	script := `SET yb_non_ddl_txn_for_sys_tables_allowed = ON;
+
+-- pg_class
+UPDATE pg_class SET reltuples = 10 WHERE relname = 'a;b';
+INSERT INTO t VALUES ('it''s; fine', "odd;name");
+DO $body$ BEGIN RAISE NOTICE 'x;y'; END $body$;
+SELECT $1; -- trailing comment
+SELECT true AS ok \gset
+\if :ok
+\echo 'a;b'
+\endif
+`
+	expected := []string{
+		"SET yb_non_ddl_txn_for_sys_tables_allowed = ON;",
+		"-- pg_class\nUPDATE pg_class SET reltuples = 10 WHERE relname = 'a;b';",
+		"INSERT INTO t VALUES ('it''s; fine', \"odd;name\");",
+		"DO $body$ BEGIN RAISE NOTICE 'x;y'; END $body$;",
+		"SELECT $1;",
+		"-- trailing comment\nSELECT true AS ok \\gset",
+		"\\if :ok",
+		"\\echo 'a;b'",
+		"\\endif",
+	}
+	if got := splitSQLStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n%q\nexpected:\n%q", got, expected)
+	}
+}
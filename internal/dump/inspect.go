@@ -0,0 +1,168 @@
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DumpSummary describes a dump directory.
+type DumpSummary struct {
+	Dir string `json:"dir"`
+	// Incomplete is the reason of the INCOMPLETE marker, if any.
+	Incomplete       string        `json:"incomplete,omitempty"`
+	ServerVersion    string        `json:"server_version,omitempty"`
+	Snapshot         *DumpSnapshot `json:"snapshot,omitempty"`
+	Relations        int           `json:"relations"`
+	ColumnStatistics int           `json:"column_statistics"`
+	Files            []string      `json:"files"`
+}
+
+// InspectDump summarizes the dump in dir.
+func InspectDump(dir string) (*DumpSummary, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dump directory: %w", err)
+	}
+	summary := &DumpSummary{Dir: dir, Files: []string{}}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		summary.Files = append(summary.Files, name)
+	}
+
+	if reason, err := os.ReadFile(filepath.Join(dir, IncompleteFile)); err == nil {
+		summary.Incomplete = firstLine(string(reason))
+	}
+	if version, err := os.ReadFile(filepath.Join(dir, VersionFile)); err == nil {
+		summary.ServerVersion = strings.TrimSpace(string(version))
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, SnapshotFile)); err == nil {
+		var snapshot DumpSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to parse snapshot.json: %w", err)
+		}
+		summary.Snapshot = &snapshot
+	}
+
+	err = readStatisticsRows(dir, func(section string, row json.RawMessage) error {
+		switch section {
+		case sectionPgClass:
+			summary.Relations++
+		case sectionPgStatistic:
+			summary.ColumnStatistics++
+		}
+		return nil
+	})
+	if err != nil && summary.Incomplete == "" {
+		return nil, err
+	}
+	return summary, nil
+}
+
+var pgMajorVersionRegexp = regexp.MustCompile(`PostgreSQL (\d+)`)
+
+// dumpPGMajorVersion returns the PostgreSQL major version recorded in the
+// version.txt of dir, e.g. 15 for "PostgreSQL 15.2-YB-2.25.0.0 ...".
+func dumpPGMajorVersion(dir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, VersionFile))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read version.txt: %w", err)
+	}
+	m := pgMajorVersionRegexp.FindSubmatch(data)
+	if m == nil {
+		return 0, fmt.Errorf("no PostgreSQL version in version.txt: %q", firstLine(string(data)))
+	}
+	return strconv.Atoi(string(m[1]))
+}
+
+// StatisticsChange is a difference between the statistics of two dumps.
+type StatisticsChange struct {
+	// Kind is "added", "removed" or "changed".
+	Kind string `json:"kind"`
+	// Object is schema.relation for pg_class rows and
+	// schema.relation.column for pg_statistic rows.
+	Object string `json:"object"`
+	// Fields lists the changed columns.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// DiffStatistics compares the statistics exported to oldDir and newDir.
+// Changes are sorted by object.
+func DiffStatistics(oldDir, newDir string) ([]StatisticsChange, error) {
+	oldRows, err := statisticsByObject(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newRows, err := statisticsByObject(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []StatisticsChange
+	for object, oldRow := range oldRows {
+		newRow, ok := newRows[object]
+		if !ok {
+			changes = append(changes, StatisticsChange{Kind: "removed", Object: object})
+			continue
+		}
+		var fields []string
+		for field, value := range oldRow {
+			if !reflect.DeepEqual(value, newRow[field]) {
+				fields = append(fields, field)
+			}
+		}
+		for field := range newRow {
+			if _, ok := oldRow[field]; !ok {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			sort.Strings(fields)
+			changes = append(changes, StatisticsChange{Kind: "changed", Object: object, Fields: fields})
+		}
+	}
+	for object := range newRows {
+		if _, ok := oldRows[object]; !ok {
+			changes = append(changes, StatisticsChange{Kind: "added", Object: object})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Object < changes[j].Object
+	})
+	return changes, nil
+}
+
+// statisticsByObject reads the statistics rows of dir by object, as in
+// StatisticsChange, without their key columns.
+func statisticsByObject(dir string) (map[string]map[string]any, error) {
+	rows := make(map[string]map[string]any)
+	err := readStatisticsRows(dir, func(section string, raw json.RawMessage) error {
+		var row map[string]any
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return fmt.Errorf("failed to parse %s row: %w", section, err)
+		}
+		key := fmt.Sprintf("%v.%v", row["nspname"], row["relname"])
+		delete(row, "nspname")
+		delete(row, "relname")
+		if section == sectionPgStatistic {
+			key += fmt.Sprintf(".%v", row["attname"])
+			if inherit, _ := row["stainherit"].(bool); inherit {
+				key += " (inherited)"
+			}
+			delete(row, "attname")
+			delete(row, "stainherit")
+		}
+		rows[key] = row
+		return nil
+	})
+	return rows, err
+}
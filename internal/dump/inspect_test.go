@@ -0,0 +1,118 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestDump(t *testing.T, statistics string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		VersionFile:        "PostgreSQL 15.2-YB-2.25.0.0-b0 on x86_64-pc-linux-gnu\n",
+		StatisticsJSONFile: statistics,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const testStatistics = `{
+    "version": "1.0.0",
+    "pg_class": [
+        {"relname":"t1","relpages":1,"reltuples":10,"relallvisible":0,"nspname":"public"},
+        {"relname":"t2","relpages":2,"reltuples":20,"relallvisible":0,"nspname":"public"}
+    ],
+    "pg_statistic": [
+        {"nspname":"public","relname":"t1","attname":"a","typnspname":"pg_catalog","typname":"int4","stainherit":false,"stanullfrac":0,"stawidth":4,"stadistinct":-1,"stakind1":0,"stakind2":0,"stakind3":0,"stakind4":0,"stakind5":0,"staop1":0,"staop2":0,"staop3":0,"staop4":0,"staop5":0,"stanumbers1":null,"stanumbers2":null,"stanumbers3":null,"stanumbers4":null,"stanumbers5":null,"stacoll1":0,"stacoll2":0,"stacoll3":0,"stacoll4":0,"stacoll5":0,"stavalues1":null,"stavalues2":null,"stavalues3":null,"stavalues4":null,"stavalues5":null}
+    ]
+}`
+
+func TestInspectDump(t *testing.T) {
+	dir := writeTestDump(t, testStatistics)
+	summary, err := InspectDump(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Relations != 2 || summary.ColumnStatistics != 1 {
+		t.Errorf("expected 2 relations and 1 column statistics, got %d and %d", summary.Relations, summary.ColumnStatistics)
+	}
+	if summary.Incomplete != "" {
+		t.Errorf("expected a complete dump, got %q", summary.Incomplete)
+	}
+	if !strings.HasPrefix(summary.ServerVersion, "PostgreSQL 15.2") {
+		t.Errorf("unexpected server version %q", summary.ServerVersion)
+	}
+
+	if err := markIncomplete(dir, "dump interrupted"); err != nil {
+		t.Fatal(err)
+	}
+	summary, err = InspectDump(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Incomplete != "dump interrupted" {
+		t.Errorf("expected the INCOMPLETE reason, got %q", summary.Incomplete)
+	}
+}
+
+func TestDiffStatistics(t *testing.T) {
+	oldDir := writeTestDump(t, testStatistics)
+	changed := strings.Replace(testStatistics, `"relpages":2,"reltuples":20`, `"relpages":3,"reltuples":30`, 1)
+	changed = strings.Replace(changed, `{"relname":"t1","relpages":1,"reltuples":10,"relallvisible":0,"nspname":"public"},`,
+		`{"relname":"t3","relpages":1,"reltuples":10,"relallvisible":0,"nspname":"public"},`, 1)
+	newDir := writeTestDump(t, changed)
+
+	changes, err := DiffStatistics(oldDir, newDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StatisticsChange{
+		{Kind: "removed", Object: "public.t1"},
+		{Kind: "changed", Object: "public.t2", Fields: []string{"relpages", "reltuples"}},
+		{Kind: "added", Object: "public.t3"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("expected %+v, got %+v", want, changes)
+	}
+
+	if changes, err := DiffStatistics(oldDir, oldDir); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v, %v", changes, err)
+	}
+}
+
+func TestRenderStatistics(t *testing.T) {
+	dir := writeTestDump(t, testStatistics)
+	var sb strings.Builder
+	if err := RenderStatistics(dir, newCatalogRenderer(&sb, true)); err != nil {
+		t.Fatal(err)
+	}
+	got := sb.String()
+	for _, want := range []string{
+		"SET yb_non_ddl_txn_for_sys_tables_allowed = ON;",
+		"UPDATE pg_class SET reltuples = 20, relpages = 2",
+		"INSERT INTO pg_statistic",
+		"SET yb_non_ddl_txn_for_sys_tables_allowed = OFF;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderStatisticsJSON(t *testing.T) {
+	dir := writeTestDump(t, testStatistics)
+	var sb strings.Builder
+	if err := RenderStatistics(dir, newJSONRenderer(&sb, false)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != testStatistics {
+		t.Errorf("expected the statistics unchanged, got:\n%s", got)
+	}
+}
@@ -0,0 +1,193 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	DefaultJobs              = 4
+	DefaultRelationBatchSize = 500
+)
+
+// exportTask is an exporter run by runTasks under the timeout of phase.
+// run returns wrapped errors.
+type exportTask struct {
+	label string
+	phase string
+	run   func(ctx context.Context, w *Dumper) error
+}
+
+// worker returns a Dumper reading the catalog on its own pooled connection,
+// in a REPEATABLE READ, READ ONLY transaction importing the dump snapshot when it was
+// exported. release must be called when the worker is done.
+func (d *Dumper) worker(ctx context.Context) (*Dumper, func(), error) {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		conn.Release()
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if d.snapshot.SnapshotID != "" {
+		// SET TRANSACTION SNAPSHOT must be the first statement of tx.
+		if _, err := tx.Exec(ctx, "SET TRANSACTION SNAPSHOT "+quoteLiteral(d.snapshot.SnapshotID)); err != nil {
+			tx.Rollback(context.Background())
+			conn.Release()
+			return nil, nil, fmt.Errorf("failed to import snapshot: %w", err)
+		}
+	}
+	if err := d.config.setTimeouts(ctx, tx); err != nil {
+		tx.Rollback(context.Background())
+		conn.Release()
+		return nil, nil, err
+	}
+
+	w := *d
+	w.db = tx
+	w.tx = tx
+	release := func() {
+		tx.Rollback(context.Background())
+		conn.Release()
+	}
+	return &w, release, nil
+}
+
+// parallel reports whether exporters run on workers. Without a pool, or
+// with a single job, everything runs in order on the dump transaction.
+func (d *Dumper) parallel() bool {
+	return d.pool != nil && d.config.Jobs > 1
+}
+
+// runTasks runs independent exporters, up to Jobs at a time. Each task
+// writes its own files, so the output does not depend on scheduling.
+func (d *Dumper) runTasks(ctx context.Context, tasks []exportTask) error {
+	if !d.parallel() {
+		for _, task := range tasks {
+			if d.config.Verbose {
+				d.logf("Exporting %s...\n", task.label)
+			}
+			err := d.runPhase(ctx, task.phase, func(ctx context.Context) error {
+				return task.run(ctx, d)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// The first failure cancels the other tasks.
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(d.config.Jobs)
+	for _, task := range tasks {
+		g.Go(func() error {
+			if d.config.Verbose {
+				d.logf("Exporting %s...\n", task.label)
+			}
+			return d.runPhase(ctx, task.phase, func(ctx context.Context) error {
+				w, release, err := d.worker(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to start %s export: %w", task.label, err)
+				}
+				defer release()
+				return task.run(ctx, w)
+			})
+		})
+	}
+	return g.Wait()
+}
+
+// queryChunked runs the query built by sqlFor for batches of relations and
+// concatenates the rows in batch order. sqlFor receives the relationFilter
+// of a batch, whose oids are passed as the query argument. Batches run
+// concurrently on workers; the pool keeps a connection per job beside those
+// held by runTasks, so batches always make progress.
+func queryChunked[T any](ctx context.Context, d *Dumper, phase string, relations []Relation, sqlFor func(filter string) string, scan func(pgx.Rows) (T, error)) ([]T, error) {
+	batches := chunkRelations(relations, d.config.RelationBatchSize)
+	if len(batches) <= 1 {
+		filter, args := relationFilter(relations)
+		return queryAll(ctx, d, phase, sqlFor(filter), args, scan)
+	}
+
+	results := make([][]T, len(batches))
+	if !d.parallel() {
+		for i, batch := range batches {
+			filter, args := relationFilter(batch)
+			rows, err := queryAll(ctx, d, phase, sqlFor(filter), args, scan)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = rows
+		}
+	} else {
+		g, ctx := errgroup.WithContext(ctx)
+		g.SetLimit(d.config.Jobs)
+		for i, batch := range batches {
+			g.Go(func() error {
+				w, release, err := d.worker(ctx)
+				if err != nil {
+					return err
+				}
+				defer release()
+				filter, args := relationFilter(batch)
+				results[i], err = queryAll(ctx, w, phase, sqlFor(filter), args, scan)
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	var all []T
+	for _, rows := range results {
+		all = append(all, rows...)
+	}
+	return all, nil
+}
+
+// queryEachChunked is queryEach over batches of relations. The batches
+// run in order on the transaction of d, so that rows go straight to out.
+func queryEachChunked(ctx context.Context, d *Dumper, phase string, relations []Relation, sqlFor func(filter string) string, out rowRewinder, each func(pgx.Rows) error) error {
+	batches := chunkRelations(relations, d.config.RelationBatchSize)
+	if len(batches) <= 1 {
+		filter, args := relationFilter(relations)
+		return queryEach(ctx, d, phase, sqlFor(filter), args, out, each)
+	}
+	for _, batch := range batches {
+		filter, args := relationFilter(batch)
+		if err := queryEach(ctx, d, phase, sqlFor(filter), args, out, each); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkRelations splits relations, without duplicates, into batches of at
+// most size relations. A size of 0 or less keeps a single batch.
+func chunkRelations(relations []Relation, size int) [][]Relation {
+	seen := make(map[uint32]bool)
+	var unique []Relation
+	for _, r := range relations {
+		if !seen[r.OID] {
+			seen[r.OID] = true
+			unique = append(unique, r)
+		}
+	}
+	if size <= 0 || len(unique) <= size {
+		return [][]Relation{unique}
+	}
+	var batches [][]Relation
+	for len(unique) > 0 {
+		n := min(size, len(unique))
+		batches = append(batches, unique[:n])
+		unique = unique[n:]
+	}
+	return batches
+}
@@ -0,0 +1,85 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestChunkRelations(t *testing.T) {
+	var rels []Relation
+	for _, oid := range []uint32{1, 2, 3, 2, 4, 5} {
+		rels = append(rels, Relation{OID: oid})
+	}
+	oids := func(batches [][]Relation) [][]uint32 {
+		var result [][]uint32
+		for _, batch := range batches {
+			var b []uint32
+			for _, r := range batch {
+				b = append(b, r.OID)
+			}
+			result = append(result, b)
+		}
+		return result
+	}
+
+	got := oids(chunkRelations(rels, 2))
+	want := [][]uint32{{1, 2}, {3, 4}, {5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := chunkRelations(rels, 0); len(got) != 1 || len(got[0]) != 5 {
+		t.Errorf("expected a single batch without duplicates, got %v", got)
+	}
+	if got := chunkRelations(rels, 10); len(got) != 1 {
+		t.Errorf("expected a single batch, got %v", got)
+	}
+}
+
+func TestRunTasksSequentialOrder(t *testing.T) {
+	d := &Dumper{config: Config{Jobs: 4}}
+	var order []string
+	var tasks []exportTask
+	for _, label := range []string{"first", "second", "third"} {
+		tasks = append(tasks, exportTask{label, label, func(_ context.Context, w *Dumper) error {
+			if w != d {
+				t.Errorf("expected tasks to run on the dumper without a pool")
+			}
+			order = append(order, label)
+			return nil
+		}})
+	}
+	if err := d.runTasks(context.Background(), tasks); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(order, []string{"first", "second", "third"}) {
+		t.Errorf("expected tasks to run in order, got %v", order)
+	}
+}
+
+func TestDumperLogConcurrent(t *testing.T) {
+	var log bytes.Buffer
+	d := NewConnDumper(nil, Config{Log: &log})
+	if d.retrier.Log != d.config.Log {
+		t.Error("expected the retrier to share the log of the dump")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				d.logf("worker %d\n", i)
+			}
+		}()
+	}
+	wg.Wait()
+	if lines := strings.Count(log.String(), "\n"); lines != 800 {
+		t.Errorf("expected 800 lines, got %d", lines)
+	}
+}
@@ -0,0 +1,96 @@
+package dump
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// PasswordOptions are the sources the connection password can be read
+// from. At most one may be set; without any, the password is left to
+// PGPASSWORD and the password file.
+type PasswordOptions struct {
+	// Prompt asks for the password on the terminal, without echo.
+	Prompt bool
+	// File is read for the password, on its first line.
+	File string
+	// Env names an environment variable holding the password.
+	Env string
+	// Stdin reads the password from the first line of standard input.
+	Stdin bool
+	// Label names the password in the prompt, "Password" by default.
+	Label string
+}
+
+// ReadPassword reads the password from the source set in opts. It returns
+// an empty password when no source is set.
+func ReadPassword(opts PasswordOptions) (string, error) {
+	sources := 0
+	for _, set := range []bool{opts.Prompt, opts.File != "", opts.Env != "", opts.Stdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", fmt.Errorf("only one password source can be used")
+	}
+
+	switch {
+	case opts.Prompt:
+		return promptPassword(opts.Label)
+	case opts.File != "":
+		f, err := os.Open(opts.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to open password file: %w", err)
+		}
+		defer f.Close()
+		return readPasswordLine(f)
+	case opts.Env != "":
+		password, ok := os.LookupEnv(opts.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", opts.Env)
+		}
+		return password, nil
+	case opts.Stdin:
+		return readPasswordLine(os.Stdin)
+	}
+	return "", nil
+}
+
+// readPasswordLine returns the first line of r, without its line ending.
+func readPasswordLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// promptPassword asks for the password named label on the controlling
+// terminal, so that standard input and output stay free for other uses.
+func promptPassword(label string) (string, error) {
+	if label == "" {
+		label = "Password"
+	}
+	// Without /dev/tty, as on Windows, prompt on the standard streams.
+	in, out := os.Stdin, os.Stderr
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		defer tty.Close()
+		in, out = tty, tty
+	}
+	if !term.IsTerminal(int(in.Fd())) {
+		return "", fmt.Errorf("cannot prompt for the password without a terminal")
+	}
+
+	fmt.Fprintf(out, "%s: ", label)
+	password, err := term.ReadPassword(int(in.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return string(password), nil
+}
@@ -0,0 +1,32 @@
+package dump
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("s3cr@t/pw\r\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ReadPassword(PasswordOptions{File: path}); err != nil || got != "s3cr@t/pw" {
+		t.Errorf("expected the first line of the file, got %q, %v", got, err)
+	}
+
+	t.Setenv("DUMP_TEST_PASSWORD", "from env")
+	if got, err := ReadPassword(PasswordOptions{Env: "DUMP_TEST_PASSWORD"}); err != nil || got != "from env" {
+		t.Errorf("expected the environment variable, got %q, %v", got, err)
+	}
+	if _, err := ReadPassword(PasswordOptions{Env: "DUMP_TEST_UNSET_PASSWORD"}); err == nil {
+		t.Errorf("expected an unset variable to be an error")
+	}
+
+	if got, err := ReadPassword(PasswordOptions{}); err != nil || got != "" {
+		t.Errorf("expected no password without a source, got %q, %v", got, err)
+	}
+	if _, err := ReadPassword(PasswordOptions{File: path, Env: "DUMP_TEST_PASSWORD"}); err == nil {
+		t.Errorf("expected several sources to be rejected")
+	}
+}
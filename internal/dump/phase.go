@@ -0,0 +1,124 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IncompleteFile marks an output directory whose dump is running, failed or
+// was interrupted. Its files must not be imported.
+const IncompleteFile = "INCOMPLETE"
+
+// Phases of a dump, as named in Config.PhaseTimeouts.
+const (
+	PhaseSnapshot           = "snapshot"
+	PhaseQueryAnalysis      = "query_analysis"
+	PhaseQueryPlan          = "query_plan"
+	PhaseTableProperties    = "table_properties"
+	PhaseExtensions         = "extensions"
+	PhaseTopology           = "topology"
+	PhaseDDL                = "ddl"
+	PhaseStatistics         = "statistics"
+	PhaseRelpages           = "relpages"
+	PhaseExtendedStatistics = "extended_statistics"
+	PhasePlannerOptions     = "planner_options"
+	PhaseVersion            = "version"
+	PhaseGUCs               = "gucs"
+	PhaseLocale             = "locale"
+)
+
+var Phases = []string{
+	PhaseSnapshot, PhaseQueryAnalysis, PhaseQueryPlan, PhaseTableProperties,
+	PhaseExtensions, PhaseTopology, PhaseDDL, PhaseStatistics, PhaseRelpages,
+	PhaseExtendedStatistics, PhasePlannerOptions, PhaseVersion, PhaseGUCs,
+	PhaseLocale,
+}
+
+// SetPhaseTimeouts parses a comma separated list of phase timeouts such as
+// "10m,statistics=1h,ddl=30m". A duration without a phase name sets the
+// timeout of every phase not listed.
+func (c *Config) SetPhaseTimeouts(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		phase, value, named := strings.Cut(item, "=")
+		if !named {
+			phase, value = "", item
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", item, err)
+		}
+		if !named {
+			c.PhaseTimeout = timeout
+			continue
+		}
+		phase = strings.TrimSpace(phase)
+		if !isPhase(phase) {
+			return fmt.Errorf("unknown phase %q, expected one of %s", phase, strings.Join(Phases, ", "))
+		}
+		if c.PhaseTimeouts == nil {
+			c.PhaseTimeouts = make(map[string]time.Duration)
+		}
+		c.PhaseTimeouts[phase] = timeout
+	}
+	return nil
+}
+
+func isPhase(name string) bool {
+	for _, phase := range Phases {
+		if phase == name {
+			return true
+		}
+	}
+	return false
+}
+
+// phaseTimeout returns the timeout of phase; zero means none.
+func (c *Config) phaseTimeout(phase string) time.Duration {
+	if timeout, ok := c.PhaseTimeouts[phase]; ok {
+		return timeout
+	}
+	return c.PhaseTimeout
+}
+
+// runPhase runs fn with a context bounded by the timeout of phase. An error
+// caused by that timeout names the phase, so that it is told apart from an
+// interrupted dump.
+func (d *Dumper) runPhase(ctx context.Context, phase string, fn func(ctx context.Context) error) error {
+	timeout := d.config.phaseTimeout(phase)
+	if timeout <= 0 {
+		return fn(ctx)
+	}
+	phaseCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := fn(phaseCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(phaseCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s phase timed out after %v: %w", phase, timeout, err)
+	}
+	return err
+}
+
+// markIncomplete writes the IncompleteFile of dir with reason.
+func markIncomplete(dir, reason string) error {
+	content := fmt.Sprintf("%s\nThe files of this directory are partial and must not be imported.\n", reason)
+	if err := os.WriteFile(filepath.Join(dir, IncompleteFile), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s marker: %w", IncompleteFile, err)
+	}
+	return nil
+}
+
+// clearIncomplete removes the IncompleteFile of a completed dump.
+func clearIncomplete(dir string) error {
+	if err := os.Remove(filepath.Join(dir, IncompleteFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s marker: %w", IncompleteFile, err)
+	}
+	return nil
+}
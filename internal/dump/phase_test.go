@@ -0,0 +1,102 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetPhaseTimeouts(t *testing.T) {
+	var cfg Config
+	if err := cfg.SetPhaseTimeouts("10m, statistics=1h,ddl=30s"); err != nil {
+		t.Fatal(err)
+	}
+	for phase, want := range map[string]time.Duration{
+		PhaseStatistics: time.Hour,
+		PhaseDDL:        30 * time.Second,
+		PhaseLocale:     10 * time.Minute,
+	} {
+		if got := cfg.phaseTimeout(phase); got != want {
+			t.Errorf("expected %s timeout %v, got %v", phase, want, got)
+		}
+	}
+
+	for _, bad := range []string{"stats=1h", "ddl=forever", "1 hour"} {
+		if err := (&Config{}).SetPhaseTimeouts(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRunPhaseTimeout(t *testing.T) {
+	d := &Dumper{config: Config{PhaseTimeouts: map[string]time.Duration{PhaseDDL: time.Millisecond}}}
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := d.runPhase(context.Background(), PhaseDDL, hang)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "ddl phase timed out after 1ms") {
+		t.Errorf("expected the ddl phase to time out, got %v", err)
+	}
+
+	// An interrupted dump is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = d.runPhase(ctx, PhaseDDL, hang)
+	if !errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected the cancellation to be returned as is, got %v", err)
+	}
+
+	// Phases without a timeout run on ctx.
+	if err := d.runPhase(context.Background(), PhaseLocale, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); ok {
+			t.Errorf("expected no deadline for the locale phase")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIncompleteMarker(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, IncompleteFile)
+
+	if err := markIncomplete(dir, "dump interrupted: context canceled"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(marker)
+	if err != nil || !strings.HasPrefix(string(data), "dump interrupted: context canceled\n") {
+		t.Errorf("unexpected marker %q: %v", data, err)
+	}
+
+	if err := clearIncomplete(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("expected the marker to be removed, got %v", err)
+	}
+	if err := clearIncomplete(dir); err != nil {
+		t.Errorf("expected a missing marker to be ignored, got %v", err)
+	}
+}
+
+func TestRunInterruptedLeavesMarker(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := Config{Host: "127.0.0.1", Port: 1, Database: "db", User: "u", OutputDir: dir}
+	if err := Run(ctx, cfg); err == nil {
+		t.Fatal("expected an interrupted dump to fail")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, IncompleteFile))
+	if err != nil || !strings.HasPrefix(string(data), "dump interrupted: ") {
+		t.Errorf("unexpected marker %q: %v", data, err)
+	}
+}
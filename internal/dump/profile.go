@@ -0,0 +1,137 @@
+package dump
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfilesFile is the config file looked up in the user config directory
+// when none is given.
+const ProfilesFile = "cbo_stat_dump/profiles.json"
+
+// ProfileConfig is a config file of named capture profiles:
+//
+//	{
+//	    "profiles": {
+//	        "prod-eu": {"conn": "postgresql://app@db1/app?sslmode=verify-full", "yb_mode": true, "j": 8},
+//	        "staging": {"h": "staging-db", "u": "app", "d": "app", "password_file": "/etc/cbo/staging.pw"}
+//	    }
+//	}
+//
+// A profile maps cbo_stat_dump flag names to their values: strings,
+// numbers, booleans, or lists of strings for the comma separated flags.
+type ProfileConfig struct {
+	Profiles map[string]map[string]json.RawMessage `json:"profiles"`
+}
+
+// DefaultProfilesPath returns the config file used when none is given.
+func DefaultProfilesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user config directory: %w", err)
+	}
+	return filepath.Join(dir, ProfilesFile), nil
+}
+
+// LoadProfile returns the options of the named profile of the config file
+// at path, as flag values by flag name.
+func LoadProfile(path, name string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var config ProfileConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for n := range config.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profile %q not found in %s, expected one of: %s", name, path, strings.Join(names, ", "))
+	}
+
+	values := make(map[string]string, len(profile))
+	for key, raw := range profile {
+		value, err := profileValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %q in profile %q: %w", key, name, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// profileValue converts a profile value to its flag value.
+func profileValue(raw json.RawMessage) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("expected a list of strings")
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("expected a string, number, boolean or list of strings")
+}
+
+// ApplyProfile sets the flags of fs to the values of profile, unless they
+// were given on the command line, so that flags override profiles. When
+// names is not nil, profile options are renamed to the flags of fs
+// through it, and options missing from it are ignored.
+func ApplyProfile(fs *flag.FlagSet, profile map[string]string, names map[string]string) error {
+	given := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	keys := make([]string, 0, len(profile))
+	for key := range profile {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := key
+		if names != nil {
+			var ok bool
+			if name, ok = names[key]; !ok {
+				continue
+			}
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown option %q in profile", key)
+		}
+		if given[name] {
+			continue
+		}
+		if err := fs.Set(name, profile[key]); err != nil {
+			return fmt.Errorf("invalid %q in profile: %w", key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package dump
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testProfiles = `{
+    "profiles": {
+        "prod-eu": {"h": "db1", "p": 5433, "yb_mode": true, "tserver_web": ["n1:9000", "n2:9000"], "phase_timeout": "10m"},
+        "staging": {"h": "staging-db", "bogus": "x"}
+    }
+}`
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	if err := os.WriteFile(path, []byte(testProfiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadProfile(path, "prod-eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"h": "db1", "p": "5433", "yb_mode": "true", "tserver_web": "n1:9000,n2:9000", "phase_timeout": "10m"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := LoadProfile(path, "prod-us"); err == nil || !strings.Contains(err.Error(), "prod-eu, staging") {
+		t.Errorf("expected a missing profile to list the profiles, got %v", err)
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	newFlags := func() (*flag.FlagSet, *Config) {
+		var cfg Config
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.StringVar(&cfg.Host, "h", "", "")
+		fs.IntVar(&cfg.Port, "p", 0, "")
+		fs.BoolVar(&cfg.YBMode, "yb_mode", false, "")
+		fs.Func("phase_timeout", "", cfg.SetPhaseTimeouts)
+		return fs, &cfg
+	}
+	profile := map[string]string{"h": "db1", "p": "5433", "yb_mode": "true", "phase_timeout": "10m"}
+
+	// Flags given on the command line override the profile.
+	fs, cfg := newFlags()
+	if err := fs.Parse([]string{"-h", "db2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyProfile(fs, profile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "db2" || cfg.Port != 5433 || !cfg.YBMode || cfg.PhaseTimeout != 10*time.Minute {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	// Renamed options; those without a name are ignored.
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	prodHost := fs.String("prod_host", "", "")
+	if err := ApplyProfile(fs, profile, map[string]string{"h": "prod_host"}); err != nil {
+		t.Fatal(err)
+	}
+	if *prodHost != "db1" {
+		t.Errorf("expected the renamed host, got %q", *prodHost)
+	}
+
+	fs, _ = newFlags()
+	if err := ApplyProfile(fs, map[string]string{"bogus": "x"}, nil); err == nil {
+		t.Errorf("expected an unknown option to be rejected")
+	}
+	if err := ApplyProfile(fs, map[string]string{"p": "many"}, nil); err == nil {
+		t.Errorf("expected an invalid value to be rejected")
+	}
+}
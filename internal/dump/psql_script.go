@@ -0,0 +1,313 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// psqlScript runs the psql scripts of a dump, such as ddl.sql and
+// import_topology.sql, on a connection, without psql. It supports the
+// meta-commands the dump writes: \set, \gset, \if, \elif, \else, \endif
+// and \echo, with :name, :'name' and :"name" interpolation. The \restrict
+// and \unrestrict lines of recent pg_dump versions are ignored.
+type psqlScript struct {
+	conn *pgx.Conn
+	vars map[string]string
+	// log receives the output of \echo.
+	log io.Writer
+	ifs []psqlIf
+}
+
+// psqlIf is the state of an open \if block.
+type psqlIf struct {
+	// outer is set when the block containing the \if runs.
+	outer bool
+	// active is set while the current branch runs, and taken once a branch
+	// ran.
+	active bool
+	taken  bool
+}
+
+func newPSQLScript(conn *pgx.Conn, log io.Writer) *psqlScript {
+	return &psqlScript{conn: conn, vars: make(map[string]string), log: log}
+}
+
+// active reports whether statements run at this point of the script.
+func (s *psqlScript) active() bool {
+	return len(s.ifs) == 0 || s.ifs[len(s.ifs)-1].active
+}
+
+// run runs script, named name in errors.
+func (s *psqlScript) run(ctx context.Context, name, script string) error {
+	for _, stmt := range splitSQLScript(script) {
+		if !stmt.meta {
+			if s.active() {
+				if _, err := s.conn.Exec(ctx, s.interpolate(stmt.text)); err != nil {
+					return fmt.Errorf("%s: failed to execute %q: %w", name, firstLine(stmt.text), err)
+				}
+			}
+			continue
+		}
+		query := strings.TrimSpace(strings.TrimSuffix(stmt.text, stmt.command))
+		if isCommentOnly(query) {
+			query = ""
+		}
+		if err := s.metaCommand(ctx, query, stmt.command); err != nil {
+			return fmt.Errorf("%s: %s: %w", name, firstLine(stmt.text), err)
+		}
+	}
+	if len(s.ifs) > 0 {
+		return fmt.Errorf("%s: unterminated \\if", name)
+	}
+	return nil
+}
+
+// metaCommand runs a meta-command, following query when it terminates one,
+// as \gset does.
+func (s *psqlScript) metaCommand(ctx context.Context, query, command string) error {
+	name, rest, _ := strings.Cut(command[1:], " ")
+	if query != "" && name != "gset" {
+		return fmt.Errorf("\\%s cannot follow a query", name)
+	}
+
+	// Conditionals are tracked in inactive branches too, so that nested
+	// blocks end where they should.
+	switch name {
+	case "if":
+		block := psqlIf{outer: s.active()}
+		if block.outer {
+			v, err := s.boolArg(rest)
+			if err != nil {
+				return err
+			}
+			block.active, block.taken = v, v
+		}
+		s.ifs = append(s.ifs, block)
+		return nil
+	case "elif", "else", "endif":
+		if len(s.ifs) == 0 {
+			return fmt.Errorf("\\%s without \\if", name)
+		}
+		block := &s.ifs[len(s.ifs)-1]
+		switch name {
+		case "elif":
+			block.active = false
+			if block.outer && !block.taken {
+				v, err := s.boolArg(rest)
+				if err != nil {
+					return err
+				}
+				block.active, block.taken = v, v
+			}
+		case "else":
+			block.active = block.outer && !block.taken
+			block.taken = true
+		case "endif":
+			s.ifs = s.ifs[:len(s.ifs)-1]
+		}
+		return nil
+	}
+	if !s.active() {
+		return nil
+	}
+
+	args, err := s.metaArgs(rest)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case "set":
+		if len(args) == 0 {
+			return fmt.Errorf("\\set without a variable name")
+		}
+		s.vars[args[0]] = strings.Join(args[1:], "")
+	case "gset":
+		if query == "" {
+			return fmt.Errorf("\\gset without a query")
+		}
+		prefix := strings.Join(args, "")
+		return s.gset(ctx, s.interpolate(query), prefix)
+	case "echo":
+		fmt.Fprintln(s.log, strings.Join(args, " "))
+	case "restrict", "unrestrict":
+	default:
+		return fmt.Errorf("unsupported psql meta-command \\%s", name)
+	}
+	return nil
+}
+
+// gset runs query, which must return a single row, and sets a variable per
+// column to its text value, as psql does. NULL columns unset the variable.
+func (s *psqlScript) gset(ctx context.Context, query, prefix string) error {
+	// The simple protocol returns the text values psql would see, such as t
+	// and f for booleans.
+	results, err := s.conn.PgConn().Exec(ctx, query).ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	if len(results) != 1 {
+		return fmt.Errorf("\\gset expects a single query, got %d", len(results))
+	}
+	result := results[0]
+	if len(result.Rows) != 1 {
+		return fmt.Errorf("\\gset expects a single row, got %d", len(result.Rows))
+	}
+	for i, field := range result.FieldDescriptions {
+		name := prefix + field.Name
+		if value := result.Rows[0][i]; value != nil {
+			s.vars[name] = string(value)
+		} else {
+			delete(s.vars, name)
+		}
+	}
+	return nil
+}
+
+// boolArg evaluates the argument of \if or \elif like psql: true, false,
+// yes, no, on, off, 1 and 0, or unique prefixes of them.
+func (s *psqlScript) boolArg(rest string) (bool, error) {
+	args, err := s.metaArgs(rest)
+	if err != nil {
+		return false, err
+	}
+	if len(args) != 1 {
+		return false, fmt.Errorf("expected a boolean expression, got %q", rest)
+	}
+	v := strings.ToLower(args[0])
+	switch {
+	case v == "":
+	case strings.HasPrefix("true", v), strings.HasPrefix("yes", v), v == "1",
+		len(v) >= 2 && strings.HasPrefix("on", v):
+		return true, nil
+	case strings.HasPrefix("false", v), strings.HasPrefix("no", v), v == "0",
+		len(v) >= 2 && strings.HasPrefix("off", v):
+		return false, nil
+	}
+	return false, fmt.Errorf("unrecognized value %q for \\if expression: Boolean expected", args[0])
+}
+
+// metaArgs splits the arguments of a meta-command at white space. Single
+// quoted parts are unquoted, with backslash escapes, and variables are
+// interpolated outside of them.
+func (s *psqlScript) metaArgs(rest string) ([]string, error) {
+	var args []string
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return args, nil
+		}
+		var arg strings.Builder
+		for rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			switch {
+			case rest[0] == '\'':
+				end := 1
+				for ; end < len(rest); end++ {
+					ch := rest[end]
+					if ch == '\\' && end+1 < len(rest) {
+						end++
+						switch rest[end] {
+						case 'n':
+							arg.WriteByte('\n')
+						case 't':
+							arg.WriteByte('\t')
+						default:
+							arg.WriteByte(rest[end])
+						}
+						continue
+					}
+					if ch == '\'' {
+						if end+1 < len(rest) && rest[end+1] == '\'' {
+							arg.WriteByte('\'')
+							end++
+							continue
+						}
+						break
+					}
+					arg.WriteByte(ch)
+				}
+				if end >= len(rest) {
+					return nil, fmt.Errorf("unterminated quoted string")
+				}
+				rest = rest[end+1:]
+			case rest[0] == ':':
+				value, n := s.variable(rest)
+				if n == 0 {
+					arg.WriteByte(':')
+					rest = rest[1:]
+					continue
+				}
+				arg.WriteString(value)
+				rest = rest[n:]
+			default:
+				arg.WriteByte(rest[0])
+				rest = rest[1:]
+			}
+		}
+		args = append(args, arg.String())
+	}
+}
+
+// variable interpolates the variable reference ref starts with: :name,
+// :'name' as a literal or :"name" as an identifier. It returns the length of
+// the reference, or 0 when s does not start with one of a set variable.
+func (s *psqlScript) variable(ref string) (string, int) {
+	quote := byte(0)
+	start := 1
+	if len(ref) > 1 && (ref[1] == '\'' || ref[1] == '"') {
+		quote = ref[1]
+		start = 2
+	}
+	end := start
+	for end < len(ref) && isVariableChar(ref[end]) {
+		end++
+	}
+	if end == start {
+		return "", 0
+	}
+	value, ok := s.vars[ref[start:end]]
+	switch {
+	case !ok:
+		return "", 0
+	case quote == 0:
+		return value, end
+	case end >= len(ref) || ref[end] != quote:
+		return "", 0
+	case quote == '\'':
+		return quoteLiteral(value), end + 1
+	default:
+		return pgx.Identifier{value}.Sanitize(), end + 1
+	}
+}
+
+func isVariableChar(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '_'
+}
+
+// interpolate replaces the variable references of a SQL statement outside
+// of quotes, dollar quotes and comments. References to unset variables and
+// :: casts are kept as they are.
+func (s *psqlScript) interpolate(sql string) string {
+	var sb strings.Builder
+	for i := 0; i < len(sql); {
+		n := 1
+		switch {
+		case strings.HasPrefix(sql[i:], "::"):
+			n = 2
+		case sql[i] == ':':
+			if value, ref := s.variable(sql[i:]); ref > 0 {
+				sb.WriteString(value)
+				i += ref
+				continue
+			}
+		default:
+			_, n = nextSQLToken(sql[i:])
+		}
+		sb.WriteString(sql[i : i+n])
+		i += n
+	}
+	return sb.String()
+}
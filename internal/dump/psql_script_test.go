@@ -0,0 +1,74 @@
+package dump
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPSQLScriptInterpolate(t *testing.T) {
+	s := newPSQLScript(nil, nil)
+	s.vars["ts"] = "fast ssd"
+	s.vars["n"] = "3"
+	for _, tc := range []struct{ sql, want string }{
+		{`ALTER TABLE t SET TABLESPACE :"ts";`, `ALTER TABLE t SET TABLESPACE "fast ssd";`},
+		{`SELECT :'ts', :n, x::text;`, `SELECT 'fast ssd', 3, x::text;`},
+		{`SELECT ':ts', ":ts", $$:ts$$ -- :ts`, `SELECT ':ts', ":ts", $$:ts$$ -- :ts`},
+		{`SELECT :unset, :'unset';`, `SELECT :unset, :'unset';`},
+	} {
+		if got := s.interpolate(tc.sql); got != tc.want {
+			t.Errorf("interpolate(%q) = %q, want %q", tc.sql, got, tc.want)
+		}
+	}
+}
+
+func TestPSQLScriptMetaArgs(t *testing.T) {
+	s := newPSQLScript(nil, nil)
+	s.vars["ts"] = "it's"
+	got, err := s.metaArgs(`'WARNING: tablespace' :'ts' 'does not exist' a:ts 'x\ty'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"WARNING: tablespace", `'it''s'`, "does not exist", "ait's", "x\ty"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if _, err := s.metaArgs(`'unterminated`); err == nil {
+		t.Error("expected an error for an unterminated string")
+	}
+}
+
+func TestPSQLScriptConditionals(t *testing.T) {
+	// Without a connection the script fails on any statement that runs.
+	script := `\set yes true
+\set no off
+\if :no
+SELECT 1;
+\elif :yes
+\echo 'elif' :yes
+\if :no
+SELECT 2;
+\else
+\echo 'nested else'
+\endif
+\else
+SELECT 3;
+\endif
+\restrict abc
+\echo 'done'
+`
+	var log strings.Builder
+	if err := newPSQLScript(nil, &log).run(context.Background(), "test.sql", script); err != nil {
+		t.Fatal(err)
+	}
+	if want := "elif true\nnested else\ndone\n"; log.String() != want {
+		t.Errorf("expected output %q, got %q", want, log.String())
+	}
+
+	for _, bad := range []string{`\if maybe` + "\n\\endif\n", `\if true` + "\n", `\endif` + "\n", `\copy t from 'x'` + "\n"} {
+		if err := newPSQLScript(nil, &log).run(context.Background(), "bad.sql", bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -20,69 +19,67 @@ type Plan struct {
 	Plans        []Plan `json:"Plans,omitempty"`
 }
 
-func (d *Dumper) GetRelationNamesInQuery(queryPath string) ([]string, error) {
-	queryBytes, err := os.ReadFile(queryPath)
+// GetRelationsInQuery returns the relations scanned by the plan of the
+// query in queryPath, resolved to their oids.
+func (d *Dumper) GetRelationsInQuery(ctx context.Context, queryPath string) ([]Relation, error) {
+	query, err := d.config.readQuery(queryPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read query file: %w", err)
+		return nil, err
 	}
-	query := string(queryBytes)
 
 	// Set GUCs if needed
 	if d.config.EnableBaseScansCostModel && d.config.YBMode {
-		_, err := d.conn.Exec(context.Background(), "SET yb_enable_base_scans_cost_model=ON")
+		_, err := d.db.Exec(ctx, "SET yb_enable_base_scans_cost_model=ON")
 		if err != nil {
 			return nil, fmt.Errorf("failed to set yb_enable_base_scans_cost_model: %w", err)
 		}
 	}
 
-	explainQuery := "EXPLAIN (FORMAT JSON) " + query
+	// VERBOSE reports the schema of each scanned relation, so that the
+	// relations are resolved as the planner did whatever the search_path.
+	explainQuery := "EXPLAIN (VERBOSE, FORMAT JSON) " + query
 
 	// pgx requires a bit of work to scan a JSON result into a byte slice if it's returned as a single column
 	// Actually, EXPLAIN returns a result set where the first column is the JSON text.
 	// However, it might be split across rows? usually EXPLAIN (FORMAT JSON) returns a single row with single column for simple queries, but let's be safe.
 	// The python script fetches all.
 
-	rows, err := d.conn.Query(context.Background(), explainQuery)
+	lines, err := queryAll(ctx, d, "query analysis", explainQuery, nil, scanString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute explain: %w", err)
 	}
-	defer rows.Close()
-
-	var sb strings.Builder
-	for rows.Next() {
-		var line string
-		if err := rows.Scan(&line); err != nil {
-			return nil, fmt.Errorf("failed to scan explain output: %w", err)
-		}
-		sb.WriteString(line)
+	refs, err := relationsInExplain(strings.Join(lines, ""))
+	if err != nil {
+		return nil, err
 	}
+	return d.resolveRelations(ctx, refs)
+}
 
+// relationsInExplain returns the relations scanned by an EXPLAIN (VERBOSE,
+// FORMAT JSON) plan, each once.
+func relationsInExplain(explainJSON string) ([]Relation, error) {
 	var explainOutput ExplainOutput
-	if err := json.Unmarshal([]byte(sb.String()), &explainOutput); err != nil {
+	if err := json.Unmarshal([]byte(explainJSON), &explainOutput); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal explain json: %w", err)
 	}
 
-	relations := make(map[string]bool)
+	relations := make(map[Relation]bool)
 	if len(explainOutput) > 0 {
 		extractRelations(explainOutput[0].Plan, relations)
 	}
 
-	var relationNames []string
+	var refs []Relation
 	for r := range relations {
-		relationNames = append(relationNames, r)
+		refs = append(refs, r)
 	}
-	return relationNames, nil
+	return refs, nil
 }
 
-func extractRelations(plan Plan, relations map[string]bool) {
+func extractRelations(plan Plan, relations map[Relation]bool) {
 	if plan.RelationName != "" {
-		// Python script does specific checks. Let's see.
-		// It appends Schema.RelationName if Schema is present, else just RelationName
-		name := plan.RelationName
-		if plan.Schema != "" {
-			name = plan.Schema + "." + plan.RelationName
-		}
-		relations[name] = true
+		// The schema is only reported by EXPLAIN VERBOSE; without it the
+		// name is resolved through the search_path.
+		relations[Relation{Schema: plan.Schema, Name: plan.RelationName}] = true
 	}
 	for _, subPlan := range plan.Plans {
 		extractRelations(subPlan, relations)
@@ -0,0 +1,126 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	DefaultStatementTimeout = 5 * time.Minute
+	DefaultLockTimeout      = 10 * time.Second
+)
+
+// setTimeouts bounds the statements of tx by the configured timeouts. They
+// are set locally, so that they end with tx. A zero timeout keeps the
+// server setting.
+func (c *Config) setTimeouts(ctx context.Context, tx pgx.Tx) error {
+	for _, t := range []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"statement_timeout", c.StatementTimeout},
+		{"lock_timeout", c.LockTimeout},
+	} {
+		if t.timeout <= 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", t.name, fmt.Sprintf("%dms", t.timeout.Milliseconds())); err != nil {
+			return fmt.Errorf("failed to set %s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// readQuery reads the query file to EXPLAIN, or returns the Query of the
+// config when queryPath is empty. Unless AllowNonSelect is set, the query
+// must be a single SELECT or WITH statement.
+func (c *Config) readQuery(queryPath string) (string, error) {
+	if queryPath == "" {
+		if err := c.checkQuery(c.Query); err != nil {
+			return "", fmt.Errorf("query rejected: %w", err)
+		}
+		return c.Query, nil
+	}
+	queryBytes, err := os.ReadFile(queryPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read query file: %w", err)
+	}
+	query := string(queryBytes)
+	if err := c.checkQuery(query); err != nil {
+		return "", fmt.Errorf("query file %s rejected, use -allow_non_select to run it anyway: %w", queryPath, err)
+	}
+	return query, nil
+}
+
+func (c *Config) checkQuery(query string) error {
+	if c.AllowNonSelect {
+		return nil
+	}
+	return validateSelectQuery(query)
+}
+
+// validateSelectQuery checks that query is a single SELECT or WITH statement
+// without data modifying clauses. The query is only EXPLAINed, in a read
+// only transaction, so this is a first line of defense against query files
+// that were not meant to be captured, such as EXPLAIN ANALYZE or DML.
+func validateSelectQuery(query string) error {
+	words, statements, meta := scanSQLWords(query)
+	switch {
+	case meta:
+		return fmt.Errorf("psql meta-commands are not allowed")
+	case statements == 0 || len(words) == 0:
+		return fmt.Errorf("no statement found")
+	case statements > 1:
+		return fmt.Errorf("%d statements found, expected one", statements)
+	case words[0] != "SELECT" && words[0] != "WITH":
+		return fmt.Errorf("%s statement found, expected SELECT or WITH", words[0])
+	}
+	for i, w := range words {
+		switch w {
+		case "INSERT", "DELETE", "MERGE", "INTO":
+			return fmt.Errorf("%s is not allowed", w)
+		case "UPDATE":
+			// SELECT ... FOR [NO KEY] UPDATE only locks rows.
+			if i == 0 || (words[i-1] != "FOR" && words[i-1] != "KEY") {
+				return fmt.Errorf("%s is not allowed", w)
+			}
+		}
+	}
+	return nil
+}
+
+// scanSQLWords returns the upper cased keywords and bare identifiers of
+// query, skipping literals, quoted identifiers and comments. statements is
+// the number of non empty statements; meta reports a psql meta-command.
+func scanSQLWords(query string) (words []string, statements int, meta bool) {
+	inStatement := false
+	for i := 0; i < len(query); {
+		kind, n := nextSQLToken(query[i:])
+		token := query[i : i+n]
+		i += n
+		switch kind {
+		case sqlSpace, sqlComment:
+			continue
+		case sqlSemicolon:
+			inStatement = false
+			continue
+		}
+
+		if !inStatement {
+			inStatement = true
+			statements++
+		}
+		switch kind {
+		case sqlMeta:
+			meta = true
+		case sqlWord:
+			words = append(words, strings.ToUpper(token))
+		}
+	}
+	return words, statements, meta
+}
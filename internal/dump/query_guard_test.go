@@ -0,0 +1,39 @@
+package dump
+
+import "testing"
+
+func TestValidateSelectQuery(t *testing.T) {
+	accepted := []string{
+		"SELECT 1",
+		"select * from t where a = 'x; DELETE FROM t';\n",
+		"-- comment; with a semicolon\nWITH x AS (SELECT 1) SELECT * FROM x;",
+		"/* block /* nested; */ comment */ (SELECT 1)",
+		"SELECT $$;insert$$, E'it\\'s; delete', \"update\" FROM t FOR UPDATE",
+		"SELECT * FROM t FOR NO KEY UPDATE;  -- trailing comment",
+		"SELECT updated_at, insert_count FROM t",
+	}
+	for _, q := range accepted {
+		if err := validateSelectQuery(q); err != nil {
+			t.Errorf("expected %q to be accepted, got %v", q, err)
+		}
+	}
+
+	rejected := []string{
+		"",
+		"-- only a comment",
+		"EXPLAIN ANALYZE SELECT 1",
+		"DELETE FROM t",
+		"SELECT 1; SELECT 2",
+		"SELECT 1; DROP TABLE t",
+		"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d",
+		"WITH u AS (UPDATE t SET a = 1 RETURNING *) SELECT * FROM u",
+		"SELECT * INTO t2 FROM t",
+		"SELECT 1 \\gexec",
+		"VACUUM t",
+	}
+	for _, q := range rejected {
+		if err := validateSelectQuery(q); err == nil {
+			t.Errorf("expected %q to be rejected", q)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package dump
+
+import "strings"
+
+// sqlKeywords are the PostgreSQL keywords that cannot be used as bare
+// identifiers everywhere: reserved, type/function name and column name
+// keywords. quote_ident() quotes them, and so does quoteIdent.
+var sqlKeywords = map[string]bool{}
+
+func init() {
+	for _, kw := range strings.Fields(`
+		all analyse analyze and any array as asc asymmetric authorization
+		between bigint binary bit boolean both case cast char character check
+		coalesce collate collation column concurrently constraint create cross
+		current_catalog current_date current_role current_schema current_time
+		current_timestamp current_user dec decimal default deferrable desc
+		distinct do else end except exists extract false fetch float for
+		foreign freeze from full grant greatest group grouping having ilike in
+		initially inner inout int integer intersect interval into is isnull
+		join json json_array json_arrayagg json_exists json_object
+		json_objectagg json_query json_scalar json_serialize json_table
+		json_value lateral leading least left like limit localtime
+		localtimestamp merge_action national natural nchar none normalize not
+		notnull null nullif numeric offset on only or order out outer overlaps
+		overlay placing position precision primary real references returning
+		right row select session_user setof similar smallint some substring
+		symmetric system_user table tablesample then time timestamp to
+		trailing treat trim true union unique user using values varchar
+		variadic verbose when where window with xmlattributes xmlconcat
+		xmlelement xmlexists xmlforest xmlnamespaces xmlparse xmlpi xmlroot
+		xmlserialize xmltable
+	`) {
+		sqlKeywords[kw] = true
+	}
+}
+
+// quoteIdent quotes an identifier like quote_ident(): only when it is not a
+// lower case name made of letters, digits, underscores and dollars, or when
+// it is a keyword.
+func quoteIdent(s string) string {
+	if isSafeIdent(s) && !sqlKeywords[s] {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func isSafeIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c == '_':
+		case i > 0 && (c >= '0' && c <= '9' || c == '$'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quoteQualified quotes a schema qualified name. An empty schema leaves
+// the name unqualified.
+func quoteQualified(schema, name string) string {
+	if schema == "" {
+		return quoteIdent(name)
+	}
+	return quoteIdent(schema) + "." + quoteIdent(name)
+}
+
+// quoteLiteral quotes a string literal like quote_literal(). Literals
+// holding a backslash use the E'...' string syntax, so that they read the
+// same whatever standard_conforming_strings is set to.
+func quoteLiteral(s string) string {
+	quoted := strings.ReplaceAll(s, "'", "''")
+	if strings.Contains(s, `\`) {
+		return `E'` + strings.ReplaceAll(quoted, `\`, `\\`) + `'`
+	}
+	return `'` + quoted + `'`
+}
+
+// regclassLiteral is a regclass constant for a schema qualified relation.
+func regclassLiteral(schema, name string) string {
+	return quoteLiteral(quoteQualified(schema, name)) + "::regclass"
+}
+
+// quoteArrayElement quotes an element of an array literal, as in '{"a", "b"}'.
+func quoteArrayElement(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// pgDumpPattern is a pg_dump -t pattern matching exactly one relation:
+// double quotes make the name case sensitive and keep *, ? and . literal.
+func pgDumpPattern(schema, name string) string {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	if schema == "" {
+		return quote(name)
+	}
+	return quote(schema) + "." + quote(name)
+}
+
+// psqlQuote quotes an argument of a psql meta-command such as \echo or
+// \set, where backslashes start escape sequences.
+func psqlQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `'` + strings.ReplaceAll(s, "'", "''") + `'`
+}
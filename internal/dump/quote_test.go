@@ -0,0 +1,144 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+// adversarialNames are identifiers that break naive quoting.
+var adversarialNames = []string{
+	"Order Items",
+	"MixedCase",
+	"o'brien",
+	`we"ird`,
+	`back\slash`,
+	"select",
+	"1st",
+	"x'); DROP TABLE t; --",
+	`a"; DROP TABLE t; --`,
+	"tëst",
+	"a.b",
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := map[string]string{
+		"users":       "users",
+		"_t1$":        "_t1$",
+		"Order Items": `"Order Items"`,
+		"MixedCase":   `"MixedCase"`,
+		`we"ird`:      `"we""ird"`,
+		"select":      `"select"`,
+		"1st":         `"1st"`,
+		"a.b":         `"a.b"`,
+		"":            `""`,
+	}
+	for in, want := range tests {
+		if got := quoteIdent(in); got != want {
+			t.Errorf("quoteIdent(%q) = %s, expected %s", in, got, want)
+		}
+	}
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	tests := map[string]string{
+		"plain":        "'plain'",
+		"o'brien":      "'o''brien'",
+		`back\slash`:   `E'back\\slash'`,
+		`it's a \test`: `E'it''s a \\test'`,
+		"":             "''",
+	}
+	for in, want := range tests {
+		if got := quoteLiteral(in); got != want {
+			t.Errorf("quoteLiteral(%q) = %s, expected %s", in, got, want)
+		}
+	}
+}
+
+func TestPgDumpPattern(t *testing.T) {
+	if got := pgDumpPattern("Sales", `Order "Items"*`); got != `"Sales"."Order ""Items""*"` {
+		t.Errorf("unexpected pattern %s", got)
+	}
+	if got := pgDumpPattern("", "t1"); got != `"t1"` {
+		t.Errorf("unexpected pattern %s", got)
+	}
+}
+
+// assertStatements checks that sql splits into exactly n statements, so that
+// no name escaped its quotes.
+func assertStatements(t *testing.T, name, sql string, n int) {
+	t.Helper()
+	if got := len(splitSQLStatements(sql)); got != n {
+		t.Errorf("%q: expected %d statements, got %d:\n%s", name, n, got, sql)
+	}
+}
+
+func TestCatalogRendererAdversarial(t *testing.T) {
+	for _, name := range adversarialNames {
+		pgClass := []PgClassStats{{Nspname: name, Relname: name, Reltuples: 10}}
+		pgStat := []PgStatisticStats{{
+			Nspname: name, Relname: name, Attname: name,
+			Typnspname: name, Typname: name,
+			Stakind1: 1, Stavalues1: []interface{}{name, `"'\`},
+		}}
+		sql := renderRows(t, RenderPGCatalog, StatisticsInfo{PGMajorVersion: 15}, pgClass, pgStat)
+		// UPDATE, then DELETE and INSERT.
+		assertStatements(t, name, sql, 3)
+		if !strings.Contains(sql, regclassLiteral(name, name)) {
+			t.Errorf("%q: expected a quoted regclass, got:\n%s", name, sql)
+		}
+	}
+
+	sql := renderRows(t, RenderPGCatalog, StatisticsInfo{PGMajorVersion: 15}, []PgClassStats{{Nspname: "Sales", Relname: "Order Items"}}, nil)
+	if !strings.Contains(sql, `relnamespace = '"Sales"'::regnamespace AND (relname = 'Order Items' OR relname = 'Order Items_pkey')`) {
+		t.Errorf("unexpected pg_class update:\n%s", sql)
+	}
+}
+
+func TestCatalogRendererExtendedAdversarial(t *testing.T) {
+	for _, name := range adversarialNames {
+		sql := renderExtendedRows(t, RenderPGCatalog, []PgStatisticExtData{{Nspname: name, Stxname: name, Stxdndistinct: `\x01`}})
+		assertStatements(t, name, sql, 2)
+		if !strings.Contains(sql, "stxnamespace = "+quoteLiteral(quoteIdent(name))+"::regnamespace AND stxname = "+quoteLiteral(name)) {
+			t.Errorf("%q: expected a quoted schema and stxname, got:\n%s", name, sql)
+		}
+	}
+
+	// Dumps without the schema of the statistics object match the name alone.
+	sql := renderExtendedRows(t, RenderPGCatalog, []PgStatisticExtData{{Stxname: "s1"}})
+	if !strings.Contains(sql, "(SELECT oid FROM pg_statistic_ext WHERE stxname='s1')") {
+		t.Errorf("unexpected statistics object lookup:\n%s", sql)
+	}
+}
+
+func TestGenerateGUCsSQLAdversarial(t *testing.T) {
+	sql := generateGUCsSQL(GUCSettingsDump{
+		Settings: []GUCSetting{{Name: "random_page_cost", Setting: "1'; DROP TABLE t; --", Level: GUCLevelServer}},
+	})
+	assertStatements(t, "random_page_cost", sql, 1)
+	if !strings.Contains(sql, "SET random_page_cost='1''; DROP TABLE t; --';") {
+		t.Errorf("expected the value to be quoted, got:\n%s", sql)
+	}
+}
+
+func TestPlannerOptionsSQLAdversarial(t *testing.T) {
+	for _, name := range adversarialNames {
+		sql := generatePlannerOptionsSQL(PlannerOptionsDump{
+			Relations: []RelationOptions{{Nspname: name, Relname: name, Relkind: "r", Options: map[string]string{"fillfactor": name}}},
+			Columns:   []ColumnOptions{{Nspname: name, Relname: name, Attname: name, StatisticsTarget: 100}},
+		})
+		assertStatements(t, name, sql, 2)
+		if !strings.Contains(sql, "ALTER TABLE "+quoteQualified(name, name)+" ALTER COLUMN "+quoteIdent(name)) {
+			t.Errorf("%q: expected quoted identifiers, got:\n%s", name, sql)
+		}
+	}
+}
+
+func TestPgDumpArgsAdversarial(t *testing.T) {
+	d := &Dumper{}
+	for _, name := range adversarialNames {
+		args, _, _ := d.pgDumpArgs([]Relation{{OID: 1, Schema: name, Name: name}})
+		if got := args[len(args)-1]; got != pgDumpPattern(name, name) {
+			t.Errorf("%q: unexpected pg_dump pattern %s", name, got)
+		}
+	}
+}
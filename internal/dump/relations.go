@@ -0,0 +1,95 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Relation is a relation the dump is restricted to. OID is set once the
+// relation has been resolved by resolveRelations.
+type Relation struct {
+	OID    uint32
+	Schema string
+	Name   string
+}
+
+func (r Relation) String() string {
+	if r.Schema == "" {
+		return r.Name
+	}
+	return r.Schema + "." + r.Name
+}
+
+// resolveRelations resolves relations to their oids, in one query. The
+// names are quoted by the server, so they are matched exactly whatever
+// their case or the characters they contain. An unqualified name is looked
+// up through the search_path. The result is sorted by schema and name.
+func (d *Dumper) resolveRelations(ctx context.Context, relations []Relation) ([]Relation, error) {
+	if len(relations) == 0 {
+		return nil, nil
+	}
+	schemas := make([]string, len(relations))
+	names := make([]string, len(relations))
+	for i, r := range relations {
+		schemas[i], names[i] = r.Schema, r.Name
+	}
+
+	type resolved struct {
+		ord int64
+		oid *uint32
+		rel Relation
+	}
+	rows, err := queryAll(ctx, d, "relations", `
+		SELECT r.ord, c.oid, COALESCE(n.nspname, ''), COALESCE(c.relname, '')
+		FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS r(nspname, relname, ord)
+			LEFT JOIN pg_class c ON c.oid = to_regclass(CASE WHEN r.nspname = ''
+				THEN format('%I', r.relname) ELSE format('%I.%I', r.nspname, r.relname) END)
+			LEFT JOIN pg_namespace n ON n.oid = c.relnamespace
+		ORDER BY r.ord
+	`, []any{schemas, names}, func(rows pgx.Rows) (resolved, error) {
+		var r resolved
+		err := rows.Scan(&r.ord, &r.oid, &r.rel.Schema, &r.rel.Name)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve relations: %w", err)
+	}
+
+	seen := make(map[uint32]bool)
+	var result []Relation
+	for _, r := range rows {
+		if r.oid == nil {
+			return nil, fmt.Errorf("relation %q does not exist", relations[r.ord-1].String())
+		}
+		if seen[*r.oid] {
+			continue
+		}
+		seen[*r.oid] = true
+		r.rel.OID = *r.oid
+		result = append(result, r.rel)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Schema != result[j].Schema {
+			return result[i].Schema < result[j].Schema
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// relationFilter restricts a pg_class query aliased as c to the given
+// relations and their indexes. The oids are passed as $1, returned in args.
+// It returns an empty filter and no args for an empty list.
+func relationFilter(relations []Relation) (filter string, args []any) {
+	if len(relations) == 0 {
+		return "", nil
+	}
+	oids := make([]uint32, len(relations))
+	for i, r := range relations {
+		oids[i] = r.OID
+	}
+	return " AND (c.oid = ANY($1::oid[]) OR c.oid IN (SELECT indexrelid FROM pg_index WHERE indrelid = ANY($1::oid[])))", []any{oids}
+}
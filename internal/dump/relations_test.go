@@ -0,0 +1,53 @@
+package dump
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRelationFilter(t *testing.T) {
+	if filter, args := relationFilter(nil); filter != "" || args != nil {
+		t.Errorf("expected no filter for an empty list, got %q %v", filter, args)
+	}
+
+	filter, args := relationFilter([]Relation{
+		{OID: 16384, Schema: "public", Name: "Order Items"},
+		{OID: 16390, Schema: "Sales", Name: "o'brien"},
+	})
+	if strings.Contains(filter, "Order Items") || strings.Contains(filter, "o'brien") {
+		t.Errorf("expected names to stay out of the SQL, got %q", filter)
+	}
+	if !strings.Contains(filter, "c.oid = ANY($1::oid[])") {
+		t.Errorf("expected an oid array parameter, got %q", filter)
+	}
+	if !reflect.DeepEqual(args, []any{[]uint32{16384, 16390}}) {
+		t.Errorf("expected the oids as the only argument, got %v", args)
+	}
+}
+
+func TestRelationsInExplain(t *testing.T) {
+	// EXPLAIN (VERBOSE, FORMAT JSON) of a join of sales.orders, in a schema
+	// outside the search_path, with a public table of the same name.
+	explain := `[{"Plan": {"Node Type": "Hash Join", "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Schema": "sales", "Alias": "o"},
+		{"Node Type": "Hash", "Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Schema": "public", "Alias": "p"}
+		]},
+		{"Node Type": "Index Scan", "Relation Name": "orders", "Schema": "sales", "Alias": "o2"}
+	]}}]`
+	refs, err := relationsInExplain(explain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].String() < refs[j].String() })
+	expected := []Relation{{Schema: "public", Name: "orders"}, {Schema: "sales", Name: "orders"}}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected %v, got %v", expected, refs)
+	}
+
+	if _, err := relationsInExplain("not json"); err == nil {
+		t.Error("expected an error for invalid explain output")
+	}
+}
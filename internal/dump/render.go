@@ -0,0 +1,205 @@
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Statistics are collected from the catalog into PgClassStats and
+// PgStatisticStats rows, which Renderers write in one format each. A new
+// target format only needs a Renderer; the catalog queries do not depend on
+// it.
+
+// Statistics formats, as accepted by NewRenderer.
+const (
+	RenderPGCatalog = "pg"
+	RenderYBCatalog = "yb"
+	RenderPGRestore = "pg_restore"
+	RenderJSON      = "json"
+	RenderNDJSON    = "ndjson"
+)
+
+var RenderFormats = []string{RenderPGCatalog, RenderYBCatalog, RenderPGRestore, RenderJSON, RenderNDJSON}
+
+const statisticsVersion = "1.0.0"
+
+// StatisticsInfo describes the statistics passed to a Renderer.
+type StatisticsInfo struct {
+	// Version is the version of the statistics.json format.
+	Version string
+	// PGMajorVersion is the major version of the source server, which
+	// decides the columns of pg_statistic.
+	PGMajorVersion int
+}
+
+// Renderer writes collected statistics in one format. Begin is called
+// first, then Class for every pg_class row, Column for every pg_statistic
+// row, and End. Rows are passed as they are read, in that order, so a
+// Renderer only holds the current one.
+type Renderer interface {
+	Begin(info StatisticsInfo) error
+	Class(cls *PgClassStats) error
+	Column(stat *PgStatisticStats) error
+	End() error
+}
+
+// ExtendedRenderer is a Renderer that can also write extended statistics,
+// between Begin and End.
+type ExtendedRenderer interface {
+	Renderer
+	Extended(data *PgStatisticExtData) error
+}
+
+// NewRenderer returns the Renderer of format writing to out.
+func NewRenderer(format string, out io.Writer) (Renderer, error) {
+	switch format {
+	case RenderPGCatalog:
+		return newCatalogRenderer(out, false), nil
+	case RenderYBCatalog:
+		return newCatalogRenderer(out, true), nil
+	case RenderPGRestore:
+		return &restoreRenderer{errWriter: errWriter{out: out}}, nil
+	case RenderJSON:
+		return newJSONRenderer(out, false), nil
+	case RenderNDJSON:
+		return newJSONRenderer(out, true), nil
+	}
+	return nil, fmt.Errorf("unknown statistics format %q, expected one of %s", format, strings.Join(RenderFormats, ", "))
+}
+
+// CatalogFormat returns the format of the catalog SQL the dump writes to
+// import_statistics.sql, for YugabyteDB or PostgreSQL.
+func CatalogFormat(ybMode bool) string {
+	if ybMode {
+		return RenderYBCatalog
+	}
+	return RenderPGCatalog
+}
+
+// RenderStatistics passes the statistics exported to dir to r, so that they
+// can be rendered again in another format.
+func RenderStatistics(dir string, r Renderer) error {
+	pgVersion, err := dumpPGMajorVersion(dir)
+	if err != nil {
+		return err
+	}
+	if err := r.Begin(StatisticsInfo{Version: statisticsVersion, PGMajorVersion: pgVersion}); err != nil {
+		return fmt.Errorf("failed to render statistics: %w", err)
+	}
+	err = readStatisticsRows(dir, func(section string, row json.RawMessage) error {
+		switch section {
+		case sectionPgClass:
+			cls, err := decodeClassStats(row)
+			if err != nil {
+				return err
+			}
+			return r.Class(cls)
+		case sectionPgStatistic:
+			stat, err := decodeStatisticStats(row)
+			if err != nil {
+				return err
+			}
+			return r.Column(stat)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.End(); err != nil {
+		return fmt.Errorf("failed to render statistics: %w", err)
+	}
+	return nil
+}
+
+// multiRenderer passes statistics to several renderers. Before a query is
+// retried it rewinds their files, and the renderers keeping state, to where
+// the query started.
+type multiRenderer struct {
+	renderers []Renderer
+	files     []*rewindableFile
+}
+
+func (m *multiRenderer) Begin(info StatisticsInfo) error {
+	for _, r := range m.renderers {
+		if err := r.Begin(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *multiRenderer) Class(cls *PgClassStats) error {
+	for _, r := range m.renderers {
+		if err := r.Class(cls); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *multiRenderer) Column(stat *PgStatisticStats) error {
+	for _, r := range m.renderers {
+		if err := r.Column(stat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *multiRenderer) End() error {
+	for _, r := range m.renderers {
+		if err := r.End(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *multiRenderer) mark() error {
+	for _, f := range m.files {
+		if err := f.setMark(); err != nil {
+			return err
+		}
+	}
+	for _, r := range m.renderers {
+		if rw, ok := r.(rowRewinder); ok {
+			if err := rw.mark(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *multiRenderer) rewind() error {
+	for _, f := range m.files {
+		if err := f.rewind(); err != nil {
+			return err
+		}
+	}
+	for _, r := range m.renderers {
+		if rw, ok := r.(rowRewinder); ok {
+			if err := rw.rewind(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// errWriter keeps the first error of a sequence of writes, which renderers
+// return from their methods.
+type errWriter struct {
+	out io.Writer
+	err error
+}
+
+func (w *errWriter) write(s string) error {
+	if w.err == nil {
+		_, w.err = io.WriteString(w.out, s)
+	}
+	return w.err
+}
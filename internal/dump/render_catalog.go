@@ -0,0 +1,366 @@
+package dump
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// catalogRenderer writes SQL updating pg_class, pg_statistic and
+// pg_statistic_ext_data directly, which needs a superuser. On YugabyteDB
+// catalog writes must be allowed first, and the catalog version is bumped
+// at the end so that other sessions see the new statistics.
+type catalogRenderer struct {
+	errWriter
+	prologue  string
+	epilogue  string
+	pgVersion int
+}
+
+func newCatalogRenderer(out io.Writer, ybMode bool) *catalogRenderer {
+	r := &catalogRenderer{errWriter: errWriter{out: out}}
+	if ybMode {
+		r.prologue = "SET yb_non_ddl_txn_for_sys_tables_allowed = ON;\n\n"
+		r.epilogue = "\n" + ybCatalogVersionBumpSQL + "SET yb_non_ddl_txn_for_sys_tables_allowed = OFF;\n"
+	}
+	return r
+}
+
+func (r *catalogRenderer) Begin(info StatisticsInfo) error {
+	r.pgVersion = info.PGMajorVersion
+	return r.write(r.prologue)
+}
+
+func (r *catalogRenderer) Class(cls *PgClassStats) error {
+	return r.write(pgClassUpdateSQL(*cls))
+}
+
+func (r *catalogRenderer) Column(stat *PgStatisticStats) error {
+	sql, err := getPgStatisticInsertQuery(r.pgVersion, *stat)
+	if err != nil {
+		return err
+	}
+	return r.write(sql + "\n")
+}
+
+func (r *catalogRenderer) Extended(data *PgStatisticExtData) error {
+	return r.write(pgStatisticExtDataSQL(*data))
+}
+
+func (r *catalogRenderer) End() error {
+	return r.write(r.epilogue)
+}
+
+func pgClassUpdateSQL(cls PgClassStats) string {
+	// Match Python format exactly
+	return fmt.Sprintf(
+		"UPDATE pg_class SET reltuples = %v, relpages = %d, relallvisible = %d WHERE relnamespace = %s::regnamespace AND (relname = %s OR relname = %s);\n",
+		cls.Reltuples, cls.Relpages, cls.Relallvisible, quoteLiteral(quoteIdent(cls.Nspname)), quoteLiteral(cls.Relname), quoteLiteral(cls.Relname+"_pkey"))
+}
+
+func getPgStatisticInsertQuery(pgMajorVersion int, stat PgStatisticStats) (string, error) {
+	columnTypes := map[string]string{
+		"stainherit":  "boolean",
+		"stanullfrac": "real",
+		"stawidth":    "integer",
+		"stadistinct": "real",
+		"stakind1":    "smallint",
+		"stakind2":    "smallint",
+		"stakind3":    "smallint",
+		"stakind4":    "smallint",
+		"stakind5":    "smallint",
+		"staop1":      "oid",
+		"staop2":      "oid",
+		"staop3":      "oid",
+		"staop4":      "oid",
+		"staop5":      "oid",
+		"stanumbers1": "real[]",
+		"stanumbers2": "real[]",
+		"stanumbers3": "real[]",
+		"stanumbers4": "real[]",
+		"stanumbers5": "real[]",
+	}
+
+	if pgMajorVersion >= 15 {
+		columnTypes["stacoll1"] = "oid"
+		columnTypes["stacoll2"] = "oid"
+		columnTypes["stacoll3"] = "oid"
+		columnTypes["stacoll4"] = "oid"
+		columnTypes["stacoll5"] = "oid"
+	}
+
+	typnspname := stat.Typnspname
+	if typnspname == "" {
+		typnspname = "pg_catalog"
+	}
+	stavaluesType := quoteQualified(typnspname, stat.Typname)
+	for i := 1; i <= 5; i++ {
+		columnTypes[fmt.Sprintf("stavalues%d", i)] = stavaluesType
+	}
+
+	var columnValues []string
+
+	orderedCols := []string{
+		"stainherit", "stanullfrac", "stawidth", "stadistinct",
+		"stakind1", "stakind2", "stakind3", "stakind4", "stakind5",
+		"staop1", "staop2", "staop3", "staop4", "staop5",
+	}
+	if pgMajorVersion >= 15 {
+		orderedCols = append(orderedCols, "stacoll1", "stacoll2", "stacoll3", "stacoll4", "stacoll5")
+	}
+	orderedCols = append(orderedCols,
+		"stanumbers1", "stanumbers2", "stanumbers3", "stanumbers4", "stanumbers5",
+		"stavalues1", "stavalues2", "stavalues3", "stavalues4", "stavalues5")
+
+	for _, col := range orderedCols {
+		typ := columnTypes[col]
+		var valStr string
+
+		switch col {
+		case "stainherit":
+			valStr = fmt.Sprintf("%t::%s", stat.Stainherit, typ)
+		case "stanullfrac":
+			valStr = fmt.Sprintf("%v::%s", stat.Stanullfrac, typ)
+		case "stawidth":
+			valStr = fmt.Sprintf("%d::%s", stat.Stawidth, typ)
+		case "stadistinct":
+			valStr = fmt.Sprintf("%v::%s", stat.Stadistinct, typ)
+		case "stakind1":
+			valStr = fmt.Sprintf("%d::%s", stat.Stakind1, typ)
+		case "stakind2":
+			valStr = fmt.Sprintf("%d::%s", stat.Stakind2, typ)
+		case "stakind3":
+			valStr = fmt.Sprintf("%d::%s", stat.Stakind3, typ)
+		case "stakind4":
+			valStr = fmt.Sprintf("%d::%s", stat.Stakind4, typ)
+		case "stakind5":
+			valStr = fmt.Sprintf("%d::%s", stat.Stakind5, typ)
+		case "staop1":
+			valStr = fmt.Sprintf("%v::%s", stat.Staop1, typ)
+		case "staop2":
+			valStr = fmt.Sprintf("%v::%s", stat.Staop2, typ)
+		case "staop3":
+			valStr = fmt.Sprintf("%v::%s", stat.Staop3, typ)
+		case "staop4":
+			valStr = fmt.Sprintf("%v::%s", stat.Staop4, typ)
+		case "staop5":
+			valStr = fmt.Sprintf("%v::%s", stat.Staop5, typ)
+		case "stacoll1":
+			valStr = fmt.Sprintf("%v::%s", stat.Stacoll1, typ)
+		case "stacoll2":
+			valStr = fmt.Sprintf("%v::%s", stat.Stacoll2, typ)
+		case "stacoll3":
+			valStr = fmt.Sprintf("%v::%s", stat.Stacoll3, typ)
+		case "stacoll4":
+			valStr = fmt.Sprintf("%v::%s", stat.Stacoll4, typ)
+		case "stacoll5":
+			valStr = fmt.Sprintf("%v::%s", stat.Stacoll5, typ)
+		case "stanumbers1":
+			valStr = formatFloatArray(stat.Stanumbers1, typ)
+		case "stanumbers2":
+			valStr = formatFloatArray(stat.Stanumbers2, typ)
+		case "stanumbers3":
+			valStr = formatFloatArray(stat.Stanumbers3, typ)
+		case "stanumbers4":
+			valStr = formatFloatArray(stat.Stanumbers4, typ)
+		case "stanumbers5":
+			valStr = formatFloatArray(stat.Stanumbers5, typ)
+		case "stavalues1":
+			valStr = formatValuesArray(stat.Stavalues1, typ)
+		case "stavalues2":
+			valStr = formatValuesArray(stat.Stavalues2, typ)
+		case "stavalues3":
+			valStr = formatValuesArray(stat.Stavalues3, typ)
+		case "stavalues4":
+			valStr = formatValuesArray(stat.Stavalues4, typ)
+		case "stavalues5":
+			valStr = formatValuesArray(stat.Stavalues5, typ)
+		}
+		columnValues = append(columnValues, valStr)
+	}
+
+	starelid := regclassLiteral(stat.Nspname, stat.Relname)
+	staattnumSubquery := fmt.Sprintf("(SELECT a.attnum FROM pg_attribute a WHERE a.attrelid = %s and a.attname = %s)", starelid, quoteLiteral(stat.Attname))
+
+	vals := strings.Join(columnValues, ", ")
+
+	query := fmt.Sprintf("DELETE FROM pg_statistic WHERE starelid = %s AND staattnum = %s;\nINSERT INTO pg_statistic VALUES (%s, %s, %s);", starelid, staattnumSubquery, starelid, staattnumSubquery, vals)
+
+	return query, nil
+}
+
+func formatFloatArray(nums []float32, typ string) string {
+	if nums == nil {
+		return "NULL::" + typ
+	}
+	var strs []string
+	for _, n := range nums {
+		strs = append(strs, fmt.Sprintf("%v", n))
+	}
+	return fmt.Sprintf("'{%s}'::%s", strings.Join(strs, ","), typ)
+}
+
+func formatValuesArray(val interface{}, typ string) string {
+	if val == nil {
+		return "NULL::" + typ
+	}
+
+	sqlArray, ok := valuesArrayLiteral(val)
+	if !ok {
+		return "NULL::" + typ
+	}
+	return fmt.Sprintf("array_in(%s, %s::regtype, -1)::anyarray", quoteLiteral(sqlArray), quoteLiteral(typ))
+}
+
+// valuesArrayLiteral returns the array literal of stavalues decoded from
+// JSON, or false when they are not an array.
+func valuesArrayLiteral(val interface{}) (string, bool) {
+	listVal, ok := val.([]interface{})
+	if !ok {
+		return "", false
+	}
+	var elements []string
+	for _, e := range listVal {
+		elements = append(elements, quoteArrayElement(fmt.Sprintf("%v", e)))
+	}
+	return "{" + strings.Join(elements, ", ") + "}", true
+}
+
+// statisticExtOidSQL selects the oid of the statistics object nspname.stxname.
+// Dumps of earlier versions have no nspname, and match the name alone.
+func statisticExtOidSQL(nspname, stxname string) string {
+	if nspname == "" {
+		return fmt.Sprintf("(SELECT oid FROM pg_statistic_ext WHERE stxname=%s)", quoteLiteral(stxname))
+	}
+	return fmt.Sprintf("(SELECT oid FROM pg_statistic_ext WHERE stxnamespace = %s::regnamespace AND stxname = %s)",
+		quoteLiteral(quoteIdent(nspname)), quoteLiteral(stxname))
+}
+
+// pgStatisticExtDataSQL replaces the extended statistics data of a statistics
+// object, found by schema and name.
+func pgStatisticExtDataSQL(data PgStatisticExtData) string {
+	var sb strings.Builder
+
+	stxdndistinct := "NULL"
+	if data.Stxdndistinct != nil {
+		stxdndistinct = quoteLiteral(fmt.Sprintf("%s", data.Stxdndistinct)) + "::bytea"
+	}
+	stxddependencies := "NULL"
+	if data.Stxddependencies != nil {
+		stxddependencies = quoteLiteral(fmt.Sprintf("%s", data.Stxddependencies)) + "::bytea"
+	}
+	stxdmcv := "NULL"
+	if data.Stxdmcv != nil {
+		stxdmcv = quoteLiteral(fmt.Sprintf("%s", data.Stxdmcv)) + "::bytea"
+	}
+
+	stxdexpr := "NULL"
+	if data.Stxdexpr != nil {
+		// Construct ARRAY[ ... ]::pg_statistic[]
+		// Each element is ( ... )
+		// This is complex nested structure.
+		// Python logic:
+		// value_str = "ARRAY["
+		// for statistic in stxdexpr: (list of dicts)
+		//    value_str += '('
+		//    for key, value in statistic.items():
+		//       ...
+
+		// We need to know the order of keys in the struct equivalent of pg_statistic type?
+		// Python iterates dict items. If dict is unordered, this is risky, but works in py3.7+.
+		// Here we have `[]interface{}` where each item is likely a `map[string]interface{}`.
+
+		var arrayElements []string
+		for _, item := range data.Stxdexpr {
+			statMap, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// We need to reconstruct the row for pg_statistic type.
+			// This is basically re-using the logic from `getPgStatisticInsertQuery` but for a composite type literal.
+			// However, `stxdexpr` column is `pg_statistic[]`.
+			// The literal syntax for composite type is `(val1, val2, ...)`
+			// We need the fields in order.
+
+			// Order of fields in `pg_statistic` type?
+			// starelid, staattnum, stainherit, stanullfrac, stawidth, stadistinct, stakind1..5, staop1..5, stacoll1..5(pg15), stanumbers1..5, stavalues1..5
+
+			// Wait, the python script iterates over keys in the map and appends them.
+			// If the map from JSON only contains non-null values, we are in trouble if we don't know the order.
+			// But `row_to_json` includes all columns even if null? Yes.
+
+			// So we need to iterate keys in a specific order that matches `pg_statistic` definition.
+			// Let's assume standard order.
+
+			keys := []string{
+				"starelid", "staattnum", "stainherit", "stanullfrac", "stawidth", "stadistinct",
+				"stakind1", "stakind2", "stakind3", "stakind4", "stakind5",
+				"staop1", "staop2", "staop3", "staop4", "staop5",
+			}
+			// Check if stacoll exists in the map to decide if we include them (PG15 detection from data)
+			if _, ok := statMap["stacoll1"]; ok {
+				keys = append(keys, "stacoll1", "stacoll2", "stacoll3", "stacoll4", "stacoll5")
+			}
+
+			keys = append(keys,
+				"stanumbers1", "stanumbers2", "stanumbers3", "stanumbers4", "stanumbers5",
+				"stavalues1", "stavalues2", "stavalues3", "stavalues4", "stavalues5",
+			)
+
+			var rowValues []string
+			for _, k := range keys {
+				val, exists := statMap[k]
+				if !exists {
+					// Should not happen if row_to_json is full
+					rowValues = append(rowValues, "NULL")
+					continue
+				}
+
+				if val == nil {
+					rowValues = append(rowValues, "NULL")
+				} else {
+					// Formatting logic
+					if strings.HasPrefix(k, "stanumbers") {
+						// List of numbers -> ARRAY[...]::real[]
+						nums, ok := val.([]interface{})
+						if !ok {
+							rowValues = append(rowValues, "NULL")
+						} else {
+							var nStrs []string
+							for _, n := range nums {
+								nStrs = append(nStrs, fmt.Sprintf("%v", n))
+							}
+							rowValues = append(rowValues, fmt.Sprintf("ARRAY[%s]::real[]", strings.Join(nStrs, ",")))
+						}
+					} else if strings.HasPrefix(k, "stavalues") {
+						// List -> array_in(...)
+						vals, ok := val.([]interface{})
+						if !ok {
+							rowValues = append(rowValues, "NULL")
+						} else {
+							var vStrs []string
+							for _, v := range vals {
+								vStrs = append(vStrs, quoteArrayElement(fmt.Sprintf("%v", v)))
+							}
+							rowValues = append(rowValues, fmt.Sprintf("array_in(%s, 'pg_catalog.int4'::regtype, -1)::anyarray", quoteLiteral("{"+strings.Join(vStrs, ",")+"}")))
+							// Python script hardcodes pg_catalog.int4 here?
+							// Yes, it seems so. Why int4? Maybe assumption about expression stats?
+						}
+					} else {
+						rowValues = append(rowValues, quoteLiteral(fmt.Sprintf("%v", val)))
+					}
+				}
+			}
+			arrayElements = append(arrayElements, fmt.Sprintf("(%s)", strings.Join(rowValues, ", ")))
+		}
+		stxdexpr = fmt.Sprintf("ARRAY[%s]::pg_statistic[]", strings.Join(arrayElements, ", "))
+	}
+
+	stxoid := statisticExtOidSQL(data.Nspname, data.Stxname)
+	sb.WriteString(fmt.Sprintf(
+		"DELETE FROM pg_statistic_ext_data WHERE stxoid = %s;\n", stxoid))
+	sb.WriteString(fmt.Sprintf(
+		"INSERT INTO pg_statistic_ext_data VALUES (%s, %t, %s, %s, %s, %s);\n",
+		stxoid, data.Stxdinherit, stxdndistinct, stxddependencies, stxdmcv, stxdexpr))
+	return sb.String()
+}
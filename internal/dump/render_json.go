@@ -0,0 +1,112 @@
+package dump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonRenderer writes statistics.json, one row per line inside indented
+// sections as the Python tool did, or statistics.ndjson, one row per line
+// tagged with its section. Rows read from the server keep their JSON as
+// returned by row_to_json.
+type jsonRenderer struct {
+	errWriter
+	ndjson bool
+
+	section  string
+	rows     int
+	markSect string
+	markRows int
+}
+
+func newJSONRenderer(out io.Writer, ndjson bool) *jsonRenderer {
+	return &jsonRenderer{errWriter: errWriter{out: out}, ndjson: ndjson}
+}
+
+func (r *jsonRenderer) Begin(info StatisticsInfo) error {
+	if r.ndjson {
+		r.write(fmt.Sprintf("{\"version\": %q}\n", info.Version))
+	} else {
+		r.write("{\n")
+		r.write(fmt.Sprintf("    \"version\": \"%s\",\n", info.Version))
+	}
+	return r.startSection(sectionPgClass)
+}
+
+func (r *jsonRenderer) Class(cls *PgClassStats) error {
+	return r.writeRow(cls.raw, cls)
+}
+
+func (r *jsonRenderer) Column(stat *PgStatisticStats) error {
+	if r.section != sectionPgStatistic {
+		if err := r.startSection(sectionPgStatistic); err != nil {
+			return err
+		}
+	}
+	return r.writeRow(stat.raw, stat)
+}
+
+func (r *jsonRenderer) End() error {
+	if r.ndjson {
+		return r.err
+	}
+	if r.section != sectionPgStatistic {
+		r.startSection(sectionPgStatistic)
+	}
+	r.endArray()
+	return r.write("\n}")
+}
+
+// startSection closes the array of the previous section and opens the one
+// of section. NDJSON rows are tagged with their section instead.
+func (r *jsonRenderer) startSection(section string) error {
+	if !r.ndjson {
+		if r.section != "" {
+			r.endArray()
+			r.write(",\n")
+		}
+		r.write(fmt.Sprintf("    \"%s\": [\n", section))
+	}
+	r.section = section
+	r.rows = 0
+	return r.err
+}
+
+func (r *jsonRenderer) endArray() {
+	if r.rows > 0 {
+		r.write("\n")
+	}
+	r.write("    ]")
+}
+
+// writeRow writes raw, or v marshaled when it was not read from the server.
+func (r *jsonRenderer) writeRow(raw RawJSON, v any) error {
+	if raw == nil {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s row: %w", r.section, err)
+		}
+		raw = data
+	}
+	if r.ndjson {
+		r.write(fmt.Sprintf("{\"%s\": %s}\n", r.section, raw))
+	} else {
+		if r.rows > 0 {
+			r.write(",\n")
+		}
+		r.write("        " + string(raw))
+	}
+	r.rows++
+	return r.err
+}
+
+func (r *jsonRenderer) mark() error {
+	r.markSect, r.markRows = r.section, r.rows
+	return nil
+}
+
+func (r *jsonRenderer) rewind() error {
+	r.section, r.rows = r.markSect, r.markRows
+	return nil
+}
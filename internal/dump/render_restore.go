@@ -0,0 +1,109 @@
+package dump
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of pg_statistic slots, as in pg_statistic.h.
+const (
+	statisticKindMCV                  = 1
+	statisticKindHistogram            = 2
+	statisticKindCorrelation          = 3
+	statisticKindMCElem               = 4
+	statisticKindDECHist              = 5
+	statisticKindRangeLengthHistogram = 6
+	statisticKindBoundsHistogram      = 7
+)
+
+// restoreRenderer writes calls to pg_restore_relation_stats and
+// pg_restore_attribute_stats, which PostgreSQL 18 provides to import
+// statistics without a superuser nor catalog writes. They have no
+// equivalent for extended statistics, and the slots of other kinds, such as
+// those of PostGIS, are left out.
+type restoreRenderer struct {
+	errWriter
+}
+
+func (r *restoreRenderer) Begin(info StatisticsInfo) error {
+	return r.err
+}
+
+func (r *restoreRenderer) Class(cls *PgClassStats) error {
+	return r.write(fmt.Sprintf(
+		"SELECT pg_catalog.pg_restore_relation_stats('schemaname', %s, 'relname', %s, 'relpages', %d::integer, 'reltuples', %v::real, 'relallvisible', %d::integer);\n",
+		quoteLiteral(cls.Nspname), quoteLiteral(cls.Relname), cls.Relpages, cls.Reltuples, cls.Relallvisible))
+}
+
+func (r *restoreRenderer) Column(stat *PgStatisticStats) error {
+	var args []string
+	arg := func(name, value string) {
+		args = append(args, quoteLiteral(name)+", "+value)
+	}
+	values := func(name string, val interface{}) {
+		if literal, ok := valuesArrayLiteral(val); ok {
+			arg(name, quoteLiteral(literal)+"::text")
+		}
+	}
+	numbers := func(name string, nums []float32) {
+		if nums != nil {
+			arg(name, formatFloatArray(nums, "real[]"))
+		}
+	}
+	first := func(name string, nums []float32) {
+		if len(nums) > 0 {
+			arg(name, fmt.Sprintf("%v::real", nums[0]))
+		}
+	}
+
+	arg("schemaname", quoteLiteral(stat.Nspname))
+	arg("relname", quoteLiteral(stat.Relname))
+	arg("attname", quoteLiteral(stat.Attname))
+	arg("inherited", fmt.Sprintf("%t", stat.Stainherit))
+	arg("null_frac", fmt.Sprintf("%v::real", stat.Stanullfrac))
+	arg("avg_width", fmt.Sprintf("%d::integer", stat.Stawidth))
+	arg("n_distinct", fmt.Sprintf("%v::real", stat.Stadistinct))
+	for _, slot := range stat.slots() {
+		switch slot.kind {
+		case statisticKindMCV:
+			values("most_common_vals", slot.values)
+			numbers("most_common_freqs", slot.numbers)
+		case statisticKindHistogram:
+			values("histogram_bounds", slot.values)
+		case statisticKindCorrelation:
+			first("correlation", slot.numbers)
+		case statisticKindMCElem:
+			values("most_common_elems", slot.values)
+			numbers("most_common_elem_freqs", slot.numbers)
+		case statisticKindDECHist:
+			numbers("elem_count_histogram", slot.numbers)
+		case statisticKindRangeLengthHistogram:
+			values("range_length_histogram", slot.values)
+			first("range_empty_frac", slot.numbers)
+		case statisticKindBoundsHistogram:
+			values("range_bounds_histogram", slot.values)
+		}
+	}
+	return r.write("SELECT pg_catalog.pg_restore_attribute_stats(" + strings.Join(args, ", ") + ");\n")
+}
+
+func (r *restoreRenderer) End() error {
+	return r.err
+}
+
+// statisticSlot is one of the five slots of a pg_statistic row.
+type statisticSlot struct {
+	kind    int16
+	numbers []float32
+	values  interface{}
+}
+
+func (s *PgStatisticStats) slots() [5]statisticSlot {
+	return [5]statisticSlot{
+		{s.Stakind1, s.Stanumbers1, s.Stavalues1},
+		{s.Stakind2, s.Stanumbers2, s.Stavalues2},
+		{s.Stakind3, s.Stanumbers3, s.Stavalues3},
+		{s.Stakind4, s.Stanumbers4, s.Stavalues4},
+		{s.Stakind5, s.Stanumbers5, s.Stavalues5},
+	}
+}
@@ -0,0 +1,104 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+// renderRows renders the rows with the Renderer of format.
+func renderRows(t *testing.T, format string, info StatisticsInfo, pgClass []PgClassStats, pgStat []PgStatisticStats) string {
+	t.Helper()
+	var sb strings.Builder
+	r, err := NewRenderer(format, &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Begin(info); err != nil {
+		t.Fatal(err)
+	}
+	for i := range pgClass {
+		if err := r.Class(&pgClass[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range pgStat {
+		if err := r.Column(&pgStat[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.End(); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+// renderExtendedRows renders data with the ExtendedRenderer of format.
+func renderExtendedRows(t *testing.T, format string, data []PgStatisticExtData) string {
+	t.Helper()
+	var sb strings.Builder
+	r, err := NewRenderer(format, &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext, ok := r.(ExtendedRenderer)
+	if !ok {
+		t.Fatalf("the %s renderer does not render extended statistics", format)
+	}
+	if err := renderExtendedStatistics(ext, data); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestRestoreRenderer(t *testing.T) {
+	var sb strings.Builder
+	r, err := NewRenderer(RenderPGRestore, &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Begin(StatisticsInfo{PGMajorVersion: 18})
+	r.Class(&PgClassStats{Nspname: "Sales", Relname: "orders", Relpages: 10, Reltuples: 1000})
+	r.Column(&PgStatisticStats{
+		Nspname: "Sales", Relname: "orders", Attname: "id",
+		Stawidth: 4, Stadistinct: -1,
+		Stakind1: statisticKindMCV, Stanumbers1: []float32{0.5, 0.25}, Stavalues1: []interface{}{"a", `b"`},
+		Stakind2: statisticKindCorrelation, Stanumbers2: []float32{0.9},
+		// Slots of kinds without an argument are left out.
+		Stakind3: 99, Stavalues3: []interface{}{"x"},
+	})
+	if err := r.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "SELECT pg_catalog.pg_restore_relation_stats('schemaname', 'Sales', 'relname', 'orders', 'relpages', 10::integer, 'reltuples', 1000::real, 'relallvisible', 0::integer);\n" +
+		"SELECT pg_catalog.pg_restore_attribute_stats('schemaname', 'Sales', 'relname', 'orders', 'attname', 'id', 'inherited', false, " +
+		"'null_frac', 0::real, 'avg_width', 4::integer, 'n_distinct', -1::real, " +
+		`'most_common_vals', E'{"a", "b\\""}'::text, 'most_common_freqs', '{0.5,0.25}'::real[], 'correlation', 0.9::real);` + "\n"
+	if got := sb.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestJSONRendererMarshalsRows(t *testing.T) {
+	var sb strings.Builder
+	r, err := NewRenderer(RenderNDJSON, &sb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Begin(StatisticsInfo{Version: "1.0.0"})
+	r.Class(&PgClassStats{Nspname: "public", Relname: "t1", Relpages: 1})
+	if err := r.End(); err != nil {
+		t.Fatal(err)
+	}
+	want := "{\"version\": \"1.0.0\"}\n" +
+		"{\"pg_class\": {\"relname\":\"t1\",\"relpages\":1,\"reltuples\":0,\"relallvisible\":0,\"nspname\":\"public\"}}\n"
+	if got := sb.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestNewRendererUnknownFormat(t *testing.T) {
+	if _, err := NewRenderer("oracle", &strings.Builder{}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
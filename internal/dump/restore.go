@@ -0,0 +1,250 @@
+package dump
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportOptions configure ImportDump and ApplyDump.
+type ImportOptions struct {
+	// YBMode runs the schema scripts of ImportDump with ysqlsh, when it is
+	// in PATH.
+	YBMode bool
+	// SkipDDL imports the statistics into an existing schema.
+	SkipDDL bool
+	// ExtendedStatistics also imports import_statistics_ext.sql, and
+	// Relpages import_relpages.sql, when they were dumped.
+	ExtendedStatistics bool
+	Relpages           bool
+	// Verify checks the statistics from a new session after the import.
+	Verify    bool
+	BatchSize int
+	Retrier   *Retrier
+	Verbose   bool
+	// Log receives the progress messages and the output of \echo; nil
+	// writes them to standard output.
+	Log io.Writer
+}
+
+func (o *ImportOptions) logWriter() io.Writer {
+	if o.Log == nil {
+		return os.Stdout
+	}
+	return o.Log
+}
+
+// checkComplete returns an error when dir holds an incomplete dump.
+func checkComplete(dir string) error {
+	reason, err := os.ReadFile(filepath.Join(dir, IncompleteFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s marker: %w", IncompleteFile, err)
+	}
+	return fmt.Errorf("%s holds an incomplete dump: %s", dir, firstLine(string(reason)))
+}
+
+// schemaScripts returns the psql scripts of a dump creating its schema and
+// planner settings, in the order they run.
+func (o *ImportOptions) schemaScripts() []string {
+	var scripts []string
+	if !o.SkipDDL {
+		scripts = append(scripts, CreateExtensionsSQLFile, DDLFile)
+	}
+	return append(scripts, ImportTopologySQLFile, ImportPlannerOptionsSQLFile)
+}
+
+// ImportDump applies the dump in dir to the database cfg connects to. The
+// schema scripts, which may hold psql meta-commands, run with psql; the
+// statistics are imported in retried batches by ImportSQLFile.
+func ImportDump(ctx context.Context, cfg Config, dir string, opts ImportOptions) error {
+	if err := checkComplete(dir); err != nil {
+		return err
+	}
+	log := opts.logWriter()
+
+	bin := "psql"
+	if opts.YBMode {
+		if _, err := exec.LookPath("ysqlsh"); err == nil {
+			bin = "ysqlsh"
+		}
+	}
+	for _, script := range opts.schemaScripts() {
+		path := filepath.Join(dir, script)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if opts.Verbose {
+			fmt.Fprintf(log, "Running %s...\n", script)
+		}
+		cmd, err := cfg.LibpqCommand(ctx, bin, "-X", "-q", "-v", "ON_ERROR_STOP=1", "-f", path)
+		if err != nil {
+			return err
+		}
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to run %s: %v, output: %s", script, err, output)
+		}
+	}
+
+	conn, err := Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	return importStatistics(ctx, conn, dir, opts)
+}
+
+// ApplyDump applies the dump in dir on conn, without psql. The schema
+// scripts run statement by statement, with the psql meta-commands of the
+// dump interpreted by psqlScript; the statistics are imported in retried
+// batches by ImportSQLFile. The scripts set session settings such as
+// search_path on conn.
+func ApplyDump(ctx context.Context, conn *pgx.Conn, dir string, opts ImportOptions) error {
+	if err := checkComplete(dir); err != nil {
+		return err
+	}
+	log := opts.logWriter()
+
+	for _, name := range opts.schemaScripts() {
+		script, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if opts.Verbose {
+			fmt.Fprintf(log, "Running %s...\n", name)
+		}
+		if err := newPSQLScript(conn, log).run(ctx, name, string(script)); err != nil {
+			return err
+		}
+	}
+	return importStatistics(ctx, conn, dir, opts)
+}
+
+// importStatistics imports the statistics of the dump in dir on conn and,
+// with Verify, checks them from a new session opened with the config of
+// conn.
+func importStatistics(ctx context.Context, conn *pgx.Conn, dir string, opts ImportOptions) error {
+	log := opts.logWriter()
+	retrier := opts.Retrier
+	if retrier == nil {
+		retrier = NewRetrier(DefaultMaxRetries, opts.Verbose)
+		retrier.Log = opts.Log
+	}
+	files := []string{ImportStatisticsSQLFile}
+	if opts.ExtendedStatistics {
+		files = append(files, ImportStatisticExtSQLFile)
+	}
+	if opts.Relpages {
+		files = append(files, ImportRelpagesSQLFile)
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); os.IsNotExist(err) && file != ImportStatisticsSQLFile {
+			continue
+		}
+		if opts.Verbose {
+			fmt.Fprintf(log, "Importing %s...\n", file)
+		}
+		if err := ImportSQLFile(ctx, conn, path, opts.BatchSize, retrier, "statistics import"); err != nil {
+			return err
+		}
+	}
+	if !opts.Verify {
+		return nil
+	}
+
+	// A new session sees the statistics only once the import invalidated
+	// the catalog caches.
+	verifyConn, err := pgx.ConnectConfig(ctx, conn.Config().Copy())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer verifyConn.Close(context.Background())
+	return VerifyImportedStatistics(ctx, verifyConn, dir)
+}
+
+// ReplayQueryPlan EXPLAINs the query of cfg on conn, a session of a
+// database the dump in dir was imported into, with the planner settings of
+// the dump. The query is read and checked like the one of a capture, and
+// EXPLAINed in a read only transaction, bounded by the timeouts of cfg and
+// rolled back. Settings the server rejects are returned as warnings.
+func ReplayQueryPlan(ctx context.Context, conn *pgx.Conn, dir string, cfg Config) (plan, warnings []string, err error) {
+	query, err := cfg.readQuery(cfg.QueryFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var settings []string
+	gucs, err := os.ReadFile(filepath.Join(dir, OverriddenGUCsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", OverriddenGUCsFile, err)
+	}
+	settings = append(settings, splitSQLStatements(string(gucs))...)
+	if cfg.YBMode && cfg.EnableBaseScansCostModel {
+		settings = append(settings, "SET yb_enable_base_scans_cost_model = ON")
+	}
+	if !cfg.YBMode {
+		// Reads the imported statistics on a patched PostgreSQL.
+		settings = append(settings, "SET enable_cbo_statistics_simulation = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+	if err := cfg.setTimeouts(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+	for _, stmt := range settings {
+		// A savepoint keeps a rejected setting from aborting the
+		// transaction.
+		if err := execInSavepoint(ctx, tx, stmt); err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", firstLine(stmt), err))
+		}
+	}
+
+	rows, err := tx.Query(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return nil, warnings, fmt.Errorf("failed to explain query: %w", err)
+	}
+	plan, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, warnings, fmt.Errorf("failed to explain query: %w", err)
+	}
+	return plan, warnings, nil
+}
+
+// execInSavepoint runs stmt in a savepoint of tx, rolled back when stmt
+// fails.
+func execInSavepoint(ctx context.Context, tx pgx.Tx, stmt string) error {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := sp.Exec(ctx, stmt); err != nil {
+		sp.Rollback(ctx)
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+// ReadQueryPlan reads the plan EXPLAINed by the dump in dir.
+func ReadQueryPlan(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, QueryPlanFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", QueryPlanFile, err)
+	}
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n"), nil
+}
@@ -0,0 +1,189 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	DefaultMaxRetries = 5
+
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+)
+
+// retryableSQLStates are error codes for which rerunning the statement is
+// expected to succeed. YugabyteDB reports catalog version mismatches and
+// read restarts as serialization failures.
+var retryableSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"55P03": true, // lock_not_available
+}
+
+// retryableYBMessages are fragments of YugabyteDB internal errors (XX000)
+// raised while tablet leaders move or the catalog is being updated.
+var retryableYBMessages = []string{
+	"catalog version mismatch",
+	"leader not ready",
+	"not the leader",
+	"leader has not yet",
+	"tablet splitting",
+	"try again",
+	"restart read required",
+}
+
+// transactionRetryableSQLStates and transactionRetryableYBMessages are
+// retryable errors that a rerun inside the same transaction cannot recover
+// from: read restarts and catalog version mismatches need a new
+// transaction, and a rerun after a lock timeout would wait for the
+// lock_timeout of the statement again.
+var (
+	transactionRetryableSQLStates = map[string]bool{
+		"40001": true,
+		"55P03": true,
+	}
+	transactionRetryableYBMessages = []string{
+		"catalog version mismatch",
+		"restart read required",
+	}
+)
+
+// isRetryable reports whether err is a transient server error.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	if retryableSQLStates[pgErr.Code] {
+		return true
+	}
+	if pgErr.Code == "XX000" {
+		msg := strings.ToLower(pgErr.Message)
+		for _, m := range retryableYBMessages {
+			if strings.Contains(msg, m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRetryableInTransaction reports whether err is a transient server error
+// that a rerun of the statement in the same transaction, after rolling back
+// to a savepoint, can recover from.
+func isRetryableInTransaction(err error) bool {
+	if !isRetryable(err) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	errors.As(err, &pgErr)
+	if transactionRetryableSQLStates[pgErr.Code] {
+		return false
+	}
+	msg := strings.ToLower(pgErr.Message)
+	for _, m := range transactionRetryableYBMessages {
+		if strings.Contains(msg, m) {
+			return false
+		}
+	}
+	return true
+}
+
+// Retrier reruns operations failing with transient errors, with exponential
+// backoff, and counts the retries per phase. It is safe for concurrent use.
+type Retrier struct {
+	MaxRetries int
+	Verbose    bool
+	// Log receives the retries reported by Verbose; nil is standard output.
+	Log io.Writer
+
+	mu      sync.Mutex
+	retries map[string]int
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetrier(maxRetries int, verbose bool) *Retrier {
+	return &Retrier{
+		MaxRetries: maxRetries,
+		Verbose:    verbose,
+		retries:    make(map[string]int),
+		sleep:      sleepContext,
+	}
+}
+
+// Do runs fn, retrying it up to MaxRetries times while it fails with a
+// retryable error. fn must be safe to rerun from the start. Once ctx is
+// done no retry is made and the backoff is cut short.
+func (r *Retrier) Do(ctx context.Context, phase string, fn func() error) error {
+	return r.do(ctx, phase, isRetryable, fn)
+}
+
+// do is Do, retrying the errors for which retryable is true.
+func (r *Retrier) do(ctx context.Context, phase string, retryable func(error) bool, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= r.MaxRetries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.retries[phase]++
+		r.mu.Unlock()
+		if r.Verbose {
+			log := r.Log
+			if log == nil {
+				log = os.Stdout
+			}
+			fmt.Fprintf(log, "Retrying %s in %v (%d/%d): %v\n", phase, delay, attempt+1, r.MaxRetries, err)
+		}
+		if err := r.sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// sleepContext waits for d, or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Retries returns the number of retries of phase.
+func (r *Retrier) Retries(phase string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.retries[phase]
+}
+
+// Summary lists the retries per phase, e.g. "locale=1, statistics=2".
+func (r *Retrier) Summary() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	phases := make([]string, 0, len(r.retries))
+	for phase := range r.retries {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	var parts []string
+	for _, phase := range phases {
+		parts = append(parts, fmt.Sprintf("%s=%d", phase, r.retries[phase]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,116 @@
+package dump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&pgconn.PgError{Code: "40001", Message: "could not serialize access"}, true},
+		{fmt.Errorf("failed to query pg_class: %w", &pgconn.PgError{Code: "40001"}), true},
+		{&pgconn.PgError{Code: "XX000", Message: "Leader not ready to serve requests"}, true},
+		{&pgconn.PgError{Code: "XX000", Message: "unexpected internal error"}, false},
+		{&pgconn.PgError{Code: "42P01", Message: "relation does not exist"}, false},
+		{errors.New("connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.expected {
+			t.Errorf("isRetryable(%v) = %t, expected %t", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestIsRetryableInTransaction(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&pgconn.PgError{Code: "40P01", Message: "deadlock detected"}, true},
+		{&pgconn.PgError{Code: "XX000", Message: "Leader not ready to serve requests"}, true},
+		// Need a new transaction.
+		{&pgconn.PgError{Code: "40001", Message: "Restart read required"}, false},
+		{&pgconn.PgError{Code: "XX000", Message: "Catalog Version Mismatch: A DDL occurred while processing this query"}, false},
+		// Would wait for the lock_timeout again.
+		{&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}, false},
+		{&pgconn.PgError{Code: "42P01", Message: "relation does not exist"}, false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableInTransaction(tt.err); got != tt.expected {
+			t.Errorf("isRetryableInTransaction(%v) = %t, expected %t", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestRetrierDo(t *testing.T) {
+	r := NewRetrier(3, false)
+	var delays []time.Duration
+	r.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	calls := 0
+	err := r.Do(context.Background(), "statistics", func() error {
+		calls++
+		if calls < 3 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("expected success after 3 calls, got %v after %d", err, calls)
+	}
+	if r.Retries("statistics") != 2 || len(delays) != 2 || delays[1] != 2*delays[0] {
+		t.Errorf("unexpected retries %d with delays %v", r.Retries("statistics"), delays)
+	}
+
+	calls = 0
+	err = r.Do(context.Background(), "locale", func() error {
+		calls++
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if err == nil || calls != 4 {
+		t.Errorf("expected failure after 4 calls, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = r.Do(context.Background(), "ddl", func() error {
+		calls++
+		return &pgconn.PgError{Code: "42601"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected no retry of a permanent error, got %d calls", calls)
+	}
+
+	if summary := r.Summary(); summary != "locale=3, statistics=2" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
+
+func TestRetrierDoCancelled(t *testing.T) {
+	r := NewRetrier(3, false)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := r.Do(ctx, "statistics", func() error {
+		calls++
+		cancel()
+		return &pgconn.PgError{Code: "40001"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected no retry once the context is cancelled, got %v after %d calls", err, calls)
+	}
+
+	if err := sleepContext(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the backoff to end with the context, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const SnapshotFile = "snapshot.json"
+
+// querier is implemented by *pgx.Conn, *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// txBeginner is implemented by *pgx.Conn and *pgxpool.Conn.
+type txBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+// DumpSnapshot records the snapshot all catalog reads of a dump were made in.
+type DumpSnapshot struct {
+	Version    string    `json:"version"`
+	Isolation  string    `json:"isolation"`
+	CapturedAt time.Time `json:"captured_at"`
+	// SnapshotID is the pg_export_snapshot() id shared with pg_dump and
+	// the parallel exporters. It is empty when the server cannot export
+	// snapshots, in which case they read the catalog in snapshots of their
+	// own.
+	SnapshotID string `json:"snapshot_id,omitempty"`
+	Warning    string `json:"warning,omitempty"`
+}
+
+// beginSnapshot starts the REPEATABLE READ, READ ONLY transaction the dump
+// reads the catalog in and exports its snapshot for pg_dump. The
+// transaction is rolled back by endSnapshot.
+func (d *Dumper) beginSnapshot(ctx context.Context, conn txBeginner) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	d.tx = tx
+	d.db = tx
+	if err := d.config.setTimeouts(ctx, tx); err != nil {
+		return err
+	}
+
+	d.snapshot = DumpSnapshot{Version: "1.0.0", Isolation: "repeatable read, read only"}
+	if err := d.queryRow(ctx, "snapshot", []any{&d.snapshot.CapturedAt}, "SELECT now()"); err != nil {
+		return fmt.Errorf("failed to get snapshot time: %w", err)
+	}
+
+	// YugabyteDB may not support exported snapshots. The savepoint keeps
+	// the transaction usable when the export fails.
+	err = d.attempt(ctx, func(q querier) error {
+		return q.QueryRow(ctx, "SELECT pg_export_snapshot()").Scan(&d.snapshot.SnapshotID)
+	})
+	if err != nil {
+		d.snapshot.Warning = fmt.Sprintf("pg_export_snapshot() failed, pg_dump and parallel exporters use their own snapshots: %v", err)
+		d.logf("Warning: %s\n", d.snapshot.Warning)
+	}
+	return nil
+}
+
+// endSnapshot ends the dump transaction, which only read the catalog. The
+// rollback does not use the dump context, so that it also runs once the
+// dump was cancelled.
+func (d *Dumper) endSnapshot() {
+	if d.tx == nil {
+		return
+	}
+	d.tx.Rollback(context.Background())
+	d.tx = nil
+	d.db = nil
+	if d.pool != nil {
+		d.db = d.pool
+	}
+}
+
+// attempt runs fn once. Inside the dump transaction fn runs under a
+// savepoint, so that a failed attempt can be retried in the same snapshot
+// when the error allows it, see isRetryableInTransaction.
+func (d *Dumper) attempt(ctx context.Context, fn func(q querier) error) error {
+	if d.tx == nil {
+		return fn(d.db)
+	}
+	sp, err := d.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := fn(sp); err != nil {
+		sp.Rollback(context.Background())
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+func (d *Dumper) ExportSnapshot() error {
+	jsonOutput, err := json.MarshalIndent(d.snapshot, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.config.OutputDir, SnapshotFile), jsonOutput, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot.json: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package dump
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPgDumpArgsSnapshot(t *testing.T) {
+	d := &Dumper{config: Config{Host: "localhost", Port: 5433, Database: "db", User: "yugabyte"}}
+
+	args, _, err := d.pgDumpArgs([]Relation{{OID: 16384, Schema: "public", Name: "t1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range args {
+		if strings.HasPrefix(a, "--snapshot=") {
+			t.Errorf("expected no --snapshot without an exported snapshot, got %v", args)
+		}
+	}
+
+	d.snapshot.SnapshotID = "00000003-0000001B-1"
+	args, _, _ = d.pgDumpArgs([]Relation{{OID: 16384, Schema: "public", Name: "t1"}})
+	if !slices.Contains(args, "--snapshot=00000003-0000001B-1") {
+		t.Errorf("expected pg_dump to use the exported snapshot, got %v", args)
+	}
+	if i := slices.Index(args, "-t"); i < 0 || args[i+1] != `"public"."t1"` {
+		t.Errorf("expected relation filter, got %v", args)
+	}
+}
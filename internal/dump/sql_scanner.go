@@ -0,0 +1,144 @@
+package dump
+
+import "strings"
+
+// sqlTokenKind is the kind of a token of a SQL script.
+type sqlTokenKind int
+
+const (
+	// sqlOther is any other single byte, such as an operator or a digit.
+	sqlOther sqlTokenKind = iota
+	sqlSpace
+	// sqlWord is a keyword or a bare identifier.
+	sqlWord
+	// sqlQuoted is a string literal, with its E, B, X or N prefix, or a
+	// quoted identifier.
+	sqlQuoted
+	sqlDollarQuoted
+	// sqlComment is a -- comment, without its newline, or a /* */ comment.
+	sqlComment
+	sqlSemicolon
+	// sqlMeta is a psql meta-command, from its backslash to the end of
+	// the line.
+	sqlMeta
+)
+
+// nextSQLToken returns the kind and length of the token s starts with. It
+// is the lexer shared by the script splitter, the psql variable
+// interpolation and the query guard. Unterminated quotes and comments run to
+// the end of s.
+func nextSQLToken(s string) (sqlTokenKind, int) {
+	ch := s[0]
+	switch {
+	case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f':
+		n := 1
+		for n < len(s) && (s[n] == ' ' || s[n] == '\t' || s[n] == '\n' || s[n] == '\r' || s[n] == '\f') {
+			n++
+		}
+		return sqlSpace, n
+	case ch == ';':
+		return sqlSemicolon, 1
+	case ch == '\\':
+		return sqlMeta, lineLength(s)
+	case ch == '-' && strings.HasPrefix(s, "--"):
+		return sqlComment, lineLength(s)
+	case ch == '/' && strings.HasPrefix(s, "/*"):
+		// Block comments nest.
+		depth := 0
+		for n := 0; n < len(s); n++ {
+			if strings.HasPrefix(s[n:], "/*") {
+				depth++
+				n++
+			} else if strings.HasPrefix(s[n:], "*/") {
+				depth--
+				n++
+				if depth == 0 {
+					return sqlComment, n + 1
+				}
+			}
+		}
+		return sqlComment, len(s)
+	case ch == '\'' || ch == '"':
+		return sqlQuoted, quotedLength(s, false)
+	case ch == '$':
+		tag := dollarQuoteTag(s)
+		if tag == "" {
+			return sqlOther, 1
+		}
+		end := strings.Index(s[len(tag):], tag)
+		if end < 0 {
+			return sqlDollarQuoted, len(s)
+		}
+		return sqlDollarQuoted, len(tag) + end + len(tag)
+	case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+		n := 1
+		for n < len(s) && isWordChar(s[n]) {
+			n++
+		}
+		// A word directly followed by a quote is a prefix such as E'', whose
+		// literal escapes quotes with a backslash.
+		if n < len(s) && s[n] == '\'' {
+			escapes := n == 1 && (ch == 'E' || ch == 'e')
+			return sqlQuoted, n + quotedLength(s[n:], escapes)
+		}
+		return sqlWord, n
+	}
+	return sqlOther, 1
+}
+
+// quotedLength returns the length of the quoted string or identifier s
+// starts with. Doubled quotes are part of it, and so are backslash escapes
+// when escapes is set.
+func quotedLength(s string, escapes bool) int {
+	quote := s[0]
+	for n := 1; n < len(s); n++ {
+		if escapes && s[n] == '\\' {
+			n++
+		} else if s[n] == quote {
+			if n+1 < len(s) && s[n+1] == quote {
+				n++
+				continue
+			}
+			return n + 1
+		}
+	}
+	return len(s)
+}
+
+// lineLength returns the length of the first line of s, without its newline.
+func lineLength(s string) int {
+	if end := strings.IndexByte(s, '\n'); end >= 0 {
+		return end
+	}
+	return len(s)
+}
+
+// dollarQuoteTag returns the $tag$ opening s, or "" if s does not start with one.
+func dollarQuoteTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		ch := s[i]
+		if ch == '$' {
+			return s[:i+1]
+		}
+		if !(ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || i > 1 && ch >= '0' && ch <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch == '$' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
+
+// isCommentOnly reports whether stmt holds nothing but comments and spaces.
+func isCommentOnly(stmt string) bool {
+	for len(stmt) > 0 {
+		kind, n := nextSQLToken(stmt)
+		if kind != sqlSpace && kind != sqlComment {
+			return false
+		}
+		stmt = stmt[n:]
+	}
+	return true
+}
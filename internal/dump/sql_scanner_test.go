@@ -0,0 +1,65 @@
+package dump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNextSQLToken(t *testing.T) {
+	tests := []struct {
+		in   string
+		kind sqlTokenKind
+		want string
+	}{
+		{"select 1", sqlWord, "select"},
+		{"a$b.c", sqlWord, "a$b"},
+		{" \t\n x", sqlSpace, " \t\n "},
+		{"; x", sqlSemicolon, ";"},
+		{"'it''s; x' y", sqlQuoted, "'it''s; x'"},
+		{`"odd""; name" y`, sqlQuoted, `"odd""; name"`},
+		{`E'it\'s; x' y`, sqlQuoted, `E'it\'s; x'`},
+		{`e'\\' y`, sqlQuoted, `e'\\'`},
+		{`'no\' y`, sqlQuoted, `'no\'`},
+		{"X'1F' y", sqlQuoted, "X'1F'"},
+		{"$$a;'b$$ y", sqlDollarQuoted, "$$a;'b$$"},
+		{"$fn$ $$ $fn$ y", sqlDollarQuoted, "$fn$ $$ $fn$"},
+		{"$1 y", sqlOther, "$"},
+		{"-- a; b\ny", sqlComment, "-- a; b"},
+		{"/* a /* b; */ c */ y", sqlComment, "/* a /* b; */ c */"},
+		{"/* unterminated", sqlComment, "/* unterminated"},
+		{"\\gset x\ny", sqlMeta, "\\gset x"},
+		{"'unterminated", sqlQuoted, "'unterminated"},
+		{"1", sqlOther, "1"},
+	}
+	for _, tt := range tests {
+		kind, n := nextSQLToken(tt.in)
+		if kind != tt.kind || tt.in[:n] != tt.want {
+			t.Errorf("nextSQLToken(%q) = %d %q, expected %d %q", tt.in, kind, tt.in[:n], tt.kind, tt.want)
+		}
+	}
+}
+
+// TestSQLScannerConsistency checks that the splitter, the interpolation and
+// the query guard agree on literals and comments.
+func TestSQLScannerConsistency(t *testing.T) {
+	script := "SELECT E'a\\';b', /* c; /* :v; */ d; */ 1;\nSELECT :v;"
+
+	expected := []string{"SELECT E'a\\';b', /* c; /* :v; */ d; */ 1;", "SELECT :v;"}
+	if got := splitSQLStatements(script); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected statements:\n%q\nexpected:\n%q", got, expected)
+	}
+
+	s := newPSQLScript(nil, nil)
+	s.vars["v"] = "2"
+	if got, want := s.interpolate(script), "SELECT E'a\\';b', /* c; /* :v; */ d; */ 1;\nSELECT 2;"; got != want {
+		t.Errorf("interpolate = %q, expected %q", got, want)
+	}
+
+	words, statements, meta := scanSQLWords(script)
+	if !reflect.DeepEqual(words, []string{"SELECT", "SELECT", "V"}) || statements != 2 || meta {
+		t.Errorf("scanSQLWords = %q %d %t", words, statements, meta)
+	}
+	if !isCommentOnly("-- a\n/* b; */\n") || isCommentOnly("/* a */ b") {
+		t.Error("unexpected isCommentOnly result")
+	}
+}
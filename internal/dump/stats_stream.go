@@ -0,0 +1,156 @@
+package dump
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const StatisticsNDJSONFile = "statistics.ndjson"
+
+const (
+	sectionPgClass     = "pg_class"
+	sectionPgStatistic = "pg_statistic"
+)
+
+// rewindableFile is a buffered output file that can be truncated back to a
+// mark, so that a query retried after a transient error does not write its
+// rows twice.
+type rewindableFile struct {
+	f    *os.File
+	w    *bufio.Writer
+	mark int64
+}
+
+func createRewindableFile(path string) (*rewindableFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &rewindableFile{f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (r *rewindableFile) Write(p []byte) (int, error) {
+	return r.w.Write(p)
+}
+
+func (r *rewindableFile) setMark() error {
+	if err := r.w.Flush(); err != nil {
+		return err
+	}
+	pos, err := r.f.Seek(0, io.SeekCurrent)
+	r.mark = pos
+	return err
+}
+
+func (r *rewindableFile) rewind() error {
+	r.w.Reset(r.f)
+	if err := r.f.Truncate(r.mark); err != nil {
+		return err
+	}
+	_, err := r.f.Seek(r.mark, io.SeekStart)
+	return err
+}
+
+func (r *rewindableFile) Close() error {
+	err := r.w.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// rowRewinder is implemented by outputs that rows are streamed to.
+// queryEach marks them before a query and rewinds them before each attempt.
+type rowRewinder interface {
+	mark() error
+	rewind() error
+}
+
+// readStatisticsRows calls fn for every row of the statistics exported to
+// dir, in either layout, without loading the whole file.
+func readStatisticsRows(dir string, fn func(section string, row json.RawMessage) error) error {
+	f, err := os.Open(filepath.Join(dir, StatisticsNDJSONFile))
+	if err == nil {
+		defer f.Close()
+		return readStatisticsNDJSON(f, fn)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to open statistics.ndjson: %w", err)
+	}
+
+	f, err = os.Open(filepath.Join(dir, StatisticsJSONFile))
+	if err != nil {
+		return fmt.Errorf("failed to open statistics.json: %w", err)
+	}
+	defer f.Close()
+	return readStatisticsJSON(f, fn)
+}
+
+func readStatisticsNDJSON(r io.Reader, fn func(section string, row json.RawMessage) error) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			var entry map[string]json.RawMessage
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fmt.Errorf("failed to parse statistics.ndjson: %w", err)
+			}
+			for section, row := range entry {
+				if section == "version" {
+					continue
+				}
+				if err := fn(section, row); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read statistics.ndjson: %w", err)
+		}
+	}
+}
+
+func readStatisticsJSON(r io.Reader, fn func(section string, row json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil {
+		return fmt.Errorf("failed to parse statistics.json: %w", err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("failed to parse statistics.json: %w", err)
+		}
+		section, _ := key.(string)
+		if section == "version" {
+			var version string
+			if err := dec.Decode(&version); err != nil {
+				return fmt.Errorf("failed to parse statistics.json: %w", err)
+			}
+			continue
+		}
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("failed to parse statistics.json: %w", err)
+		}
+		for dec.More() {
+			var row json.RawMessage
+			if err := dec.Decode(&row); err != nil {
+				return fmt.Errorf("failed to parse statistics.json: %w", err)
+			}
+			if err := fn(section, row); err != nil {
+				return err
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("failed to parse statistics.json: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package dump
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONRendererRawRows(t *testing.T) {
+	// Rows as returned by the server are written as they are.
+	got := renderRows(t, RenderJSON, StatisticsInfo{Version: "1.0.0"},
+		[]PgClassStats{{raw: RawJSON(`{"relname":"t1"}`)}, {raw: RawJSON(`{"relname":"t2"}`)}}, nil)
+	want := "{\n" +
+		"    \"version\": \"1.0.0\",\n" +
+		"    \"pg_class\": [\n" +
+		"        {\"relname\":\"t1\"},\n" +
+		"        {\"relname\":\"t2\"}\n" +
+		"    ],\n" +
+		"    \"pg_statistic\": [\n" +
+		"    ]\n" +
+		"}"
+	if got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestStatisticsWriterRewind(t *testing.T) {
+	dir := t.TempDir()
+	for _, ndjson := range []bool{false, true} {
+		jsonFile := StatisticsJSONFile
+		if ndjson {
+			jsonFile = StatisticsNDJSONFile
+		}
+		jsonOut, err := createRewindableFile(filepath.Join(dir, jsonFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sqlOut, err := createRewindableFile(filepath.Join(dir, ImportStatisticsSQLFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := &multiRenderer{
+			renderers: []Renderer{newJSONRenderer(jsonOut, ndjson), newCatalogRenderer(sqlOut, false)},
+			files:     []*rewindableFile{jsonOut, sqlOut},
+		}
+		writeRow := func(row string) {
+			cls, err := decodeClassStats([]byte(row))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Class(cls)
+		}
+
+		w.Begin(StatisticsInfo{Version: "1.0.0"})
+		writeRow(`{"relname":"t1","relpages":1,"reltuples":10,"relallvisible":0,"nspname":"public"}`)
+		if err := w.mark(); err != nil {
+			t.Fatal(err)
+		}
+		// A failed attempt writes a row, then the query is retried.
+		writeRow(`{"relname":"partial","relpages":1,"reltuples":10,"relallvisible":0,"nspname":"public"}`)
+		if err := w.rewind(); err != nil {
+			t.Fatal(err)
+		}
+		writeRow(`{"relname":"t2","relpages":2,"reltuples":20,"relallvisible":0,"nspname":"public"}`)
+		if err := w.End(); err != nil {
+			t.Fatal(err)
+		}
+		jsonOut.Close()
+		sqlOut.Close()
+
+		var relnames []string
+		err = readStatisticsRows(dir, func(section string, row json.RawMessage) error {
+			var cls PgClassStats
+			if err := json.Unmarshal(row, &cls); err != nil {
+				return err
+			}
+			relnames = append(relnames, section+":"+cls.Relname)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ndjson=%t: %v", ndjson, err)
+		}
+		if strings.Join(relnames, ",") != "pg_class:t1,pg_class:t2" {
+			t.Errorf("ndjson=%t: expected rows t1 and t2, got %v", ndjson, relnames)
+		}
+
+		sql, _ := os.ReadFile(filepath.Join(dir, ImportStatisticsSQLFile))
+		if strings.Contains(string(sql), "partial") || strings.Count(string(sql), "UPDATE pg_class") != 2 {
+			t.Errorf("ndjson=%t: expected the rewound row to be dropped from the SQL, got:\n%s", ndjson, sql)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ybCurrentDBOidSQL is the oid of the database the import runs in.
+const ybCurrentDBOidSQL = "(SELECT oid FROM pg_database WHERE datname = current_database())"
+
+// ybCatalogVersionBumpSQL increments the catalog version so that other
+// backends invalidate their caches and pick up the imported statistics.
+// Without per-database catalog versions pg_yb_catalog_version has a single
+// row for template1 (db_oid 1); with ysql_enable_db_catalog_version_mode it
+// has one row per database and the row of the current database is bumped.
+// The mode is detected from the rows of pg_yb_catalog_version alone, as the
+// server does not expose the setting to SQL in every version.
+const ybCatalogVersionBumpSQL = `UPDATE pg_yb_catalog_version SET current_version = current_version + 1
+WHERE db_oid = COALESCE((SELECT db_oid FROM pg_yb_catalog_version WHERE db_oid = ` + ybCurrentDBOidSQL + `), 1);
+`
+
+// ErrStatisticsNotVisible is returned by VerifyImportedStatistics when
+// imported statistics are missing or stale.
+var ErrStatisticsNotVisible = errors.New("imported statistics are not visible in a new session")
+
+// VerifyImportedStatistics checks that the statistics exported to dir are
+// visible on conn. It is meant to run on a session opened after the import,
+// so that a missing catalog version bump shows up as stale statistics.
+//
+// Catalog reads return the committed rows whether or not the caches were
+// invalidated, so where the planner reads the imported statistics, on
+// YugabyteDB or with enable_cbo_statistics_simulation, the row estimates of
+// EXPLAIN are compared with them instead. On other servers, which invalidate
+// their caches on commit, the catalog rows are compared.
+func VerifyImportedStatistics(ctx context.Context, conn *pgx.Conn, dir string) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(context.Background())
+
+	planned, err := planWithImportedStatistics(ctx, tx, dir)
+	if err != nil {
+		return err
+	}
+	v := &statisticsVerifier{tx: tx, planned: planned, tableRows: make(map[string]float64)}
+	err = readStatisticsRows(dir, func(section string, row json.RawMessage) error {
+		switch section {
+		case sectionPgClass:
+			var cls PgClassStats
+			if err := json.Unmarshal(row, &cls); err != nil {
+				return fmt.Errorf("failed to parse pg_class row: %w", err)
+			}
+			return v.class(ctx, &cls)
+		case sectionPgStatistic:
+			var stat verifiedStatistic
+			if err := json.Unmarshal(row, &stat); err != nil {
+				return fmt.Errorf("failed to parse pg_statistic row: %w", err)
+			}
+			return v.column(ctx, &stat)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(v.problems) > 0 {
+		return fmt.Errorf("%w:\n  %s", ErrStatisticsNotVisible, strings.Join(v.problems, "\n  "))
+	}
+	return nil
+}
+
+// planWithImportedStatistics applies the planner settings of the dump in dir
+// to tx and reports whether the planner of the server reads the imported
+// statistics. Settings the server rejects are skipped.
+func planWithImportedStatistics(ctx context.Context, tx pgx.Tx, dir string) (bool, error) {
+	gucs, err := os.ReadFile(filepath.Join(dir, OverriddenGUCsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("failed to read %s: %w", OverriddenGUCsFile, err)
+	}
+	for _, stmt := range splitSQLStatements(string(gucs)) {
+		execInSavepoint(ctx, tx, stmt)
+	}
+
+	var yb bool
+	if err := tx.QueryRow(ctx, "SELECT to_regclass('pg_catalog.pg_yb_catalog_version') IS NOT NULL").Scan(&yb); err != nil {
+		return false, fmt.Errorf("failed to detect YugabyteDB: %w", err)
+	}
+	if yb {
+		return true, nil
+	}
+	// Reads the imported statistics on a patched PostgreSQL.
+	return execInSavepoint(ctx, tx, "SET enable_cbo_statistics_simulation = ON") == nil, nil
+}
+
+// verifiedStatistic is the part of a pg_statistic row that is verified.
+type verifiedStatistic struct {
+	Nspname     string  `json:"nspname"`
+	Relname     string  `json:"relname"`
+	Attname     string  `json:"attname"`
+	Stainherit  bool    `json:"stainherit"`
+	Stanullfrac float32 `json:"stanullfrac"`
+}
+
+// statisticsVerifier collects the problems found by VerifyImportedStatistics.
+type statisticsVerifier struct {
+	tx      pgx.Tx
+	planned bool
+	// tableRows holds the estimated rows of the tables checked so far, by
+	// qualified name.
+	tableRows map[string]float64
+	problems  []string
+}
+
+func (v *statisticsVerifier) class(ctx context.Context, cls *PgClassStats) error {
+	var relkind string
+	var reltuples float32
+	err := v.tx.QueryRow(ctx, `
+		SELECT c.relkind::text, c.reltuples FROM pg_class c JOIN pg_namespace n ON c.relnamespace = n.oid
+		WHERE n.nspname = $1 AND c.relname = $2`, cls.Nspname, cls.Relname).Scan(&relkind, &reltuples)
+	if errors.Is(err, pgx.ErrNoRows) {
+		v.problems = append(v.problems, fmt.Sprintf("relation %s.%s does not exist", cls.Nspname, cls.Relname))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query pg_class: %w", err)
+	}
+
+	if !v.planned {
+		if reltuples != cls.Reltuples {
+			v.problems = append(v.problems, fmt.Sprintf("%s.%s has reltuples %v, expected %v", cls.Nspname, cls.Relname, reltuples, cls.Reltuples))
+		}
+		return nil
+	}
+	// Only the scans of tables are estimated from reltuples alone; -1 is
+	// a table never analyzed.
+	if (relkind != "r" && relkind != "m") || cls.Reltuples < 0 {
+		return nil
+	}
+	table := quoteQualified(cls.Nspname, cls.Relname)
+	rows, err := v.planRows(ctx, "SELECT * FROM ONLY "+table)
+	if err != nil {
+		return err
+	}
+	v.tableRows[table] = rows
+	if !rowEstimateMatches(rows, float64(cls.Reltuples)) {
+		v.problems = append(v.problems, fmt.Sprintf("%s.%s is estimated at %v rows, expected %v from the imported reltuples", cls.Nspname, cls.Relname, rows, cls.Reltuples))
+	}
+	return nil
+}
+
+func (v *statisticsVerifier) column(ctx context.Context, stat *verifiedStatistic) error {
+	if !v.planned {
+		var found bool
+		err := v.tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM pg_statistic s
+				JOIN pg_class c ON c.oid = s.starelid
+				JOIN pg_namespace n ON c.relnamespace = n.oid
+				JOIN pg_attribute a ON a.attrelid = s.starelid AND a.attnum = s.staattnum
+			WHERE n.nspname = $1 AND c.relname = $2 AND a.attname = $3 AND s.stainherit = $4)`,
+			stat.Nspname, stat.Relname, stat.Attname, stat.Stainherit).Scan(&found)
+		if err != nil {
+			return fmt.Errorf("failed to query pg_statistic: %w", err)
+		}
+		if !found {
+			v.problems = append(v.problems, fmt.Sprintf("no statistics for %s.%s.%s (inherited: %t)", stat.Nspname, stat.Relname, stat.Attname, stat.Stainherit))
+		}
+		return nil
+	}
+	// The null fraction of the column sets the estimate of IS NULL on the
+	// table alone.
+	table := quoteQualified(stat.Nspname, stat.Relname)
+	tableRows, ok := v.tableRows[table]
+	if stat.Stainherit || !ok {
+		return nil
+	}
+	rows, err := v.planRows(ctx, "SELECT * FROM ONLY "+table+" WHERE "+quoteIdent(stat.Attname)+" IS NULL")
+	if err != nil {
+		return err
+	}
+	if expected := tableRows * float64(stat.Stanullfrac); !rowEstimateMatches(rows, expected) {
+		v.problems = append(v.problems, fmt.Sprintf("%s.%s.%s IS NULL is estimated at %v rows, expected %v from the imported null_frac",
+			stat.Nspname, stat.Relname, stat.Attname, rows, clampRowEstimate(expected)))
+	}
+	return nil
+}
+
+// planRows returns the rows the planner estimates for query.
+func (v *statisticsVerifier) planRows(ctx context.Context, query string) (float64, error) {
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	var out string
+	if err := v.tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&out); err != nil {
+		return 0, fmt.Errorf("failed to explain %s: %w", query, err)
+	}
+	if err := json.Unmarshal([]byte(out), &plan); err != nil || len(plan) == 0 {
+		return 0, fmt.Errorf("failed to parse the plan of %s: %v", query, err)
+	}
+	return plan[0].Plan.Rows, nil
+}
+
+// clampRowEstimate rounds rows like the planner does: to a whole number of
+// at least 1.
+func clampRowEstimate(rows float64) float64 {
+	if rows <= 1 {
+		return 1
+	}
+	return math.Round(rows)
+}
+
+// rowEstimateMatches reports whether the planner estimate rows is the one
+// expected from the imported statistics, give or take the rounding of
+// reltuples, a float4.
+func rowEstimateMatches(rows, expected float64) bool {
+	expected = clampRowEstimate(expected)
+	return math.Abs(rows-expected) <= math.Max(1, expected*1e-6)
+}
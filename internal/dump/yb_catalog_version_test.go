@@ -0,0 +1,43 @@
+package dump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestYBCatalogVersionBumpSQL(t *testing.T) {
+	sql := ybCatalogVersionBumpSQL
+	assertStatements(t, "catalog version bump", sql, 1)
+
+	// The row of the current database in per-database mode, the one of
+	// template1 otherwise, without reading the mode setting.
+	want := "WHERE db_oid = COALESCE((SELECT db_oid FROM pg_yb_catalog_version WHERE db_oid = (SELECT oid FROM pg_database WHERE datname = current_database())), 1);"
+	if !strings.Contains(sql, want) {
+		t.Errorf("expected %q, got:\n%s", want, sql)
+	}
+	if strings.Contains(sql, "current_setting") {
+		t.Errorf("expected the mode to be detected from the rows alone, got:\n%s", sql)
+	}
+}
+
+func TestRowEstimateMatches(t *testing.T) {
+	tests := []struct {
+		rows, expected float64
+		want           bool
+	}{
+		{1000, 1000, true},
+		{1000, 999.6, true},
+		{1, 0, true},
+		{1, 0.3, true},
+		{12345679, 12345678.5, true},
+		// The default estimate of a table whose statistics are stale.
+		{1000, 50000, false},
+		// The default selectivity of IS NULL without statistics.
+		{5, 200, false},
+	}
+	for _, tt := range tests {
+		if got := rowEstimateMatches(tt.rows, tt.expected); got != tt.want {
+			t.Errorf("rowEstimateMatches(%v, %v) = %t, expected %t", tt.rows, tt.expected, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package dump
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	YSQLPgConfCSVFlag   = "ysql_pg_conf_csv"
+	YSQLPgConfCSVSource = "ysql_pg_conf_csv"
+)
+
+// GUCMismatch is a setting whose value in ysql_pg_conf_csv differs from the
+// value the capture session saw in pg_settings.
+type GUCMismatch struct {
+	Name          string `json:"name"`
+	FlagValue     string `json:"flag_value"`
+	SessionValue  string `json:"session_value"`
+	SessionSource string `json:"session_source"`
+}
+
+// TServerFlag returns the value of a tserver flag. For a flag that differs
+// between nodes the value of the first node is returned.
+func (g *GFlagsDump) TServerFlag(name string) (string, bool) {
+	if value, ok := g.TServer[name]; ok {
+		return value, true
+	}
+	for _, node := range g.Nodes {
+		if node.Role != GFlagsRoleTServer {
+			continue
+		}
+		if value, ok := g.Differing[GFlagsRoleTServer][name][node.Endpoint]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// parseYSQLPgConfCSV parses the ysql_pg_conf_csv flag, a CSV list of
+// postgresql.conf style name=value entries such as
+// `work_mem=16MB,"log_line_prefix='%m [%p] '"`.
+func parseYSQLPgConfCSV(value string) ([]GUCSetting, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	r := csv.NewReader(strings.NewReader(value))
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	fields, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", YSQLPgConfCSVFlag, err)
+	}
+
+	var settings []GUCSetting
+	for _, field := range fields {
+		name, setting, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		setting = strings.TrimSpace(setting)
+		if len(setting) >= 2 && strings.HasPrefix(setting, "'") && strings.HasSuffix(setting, "'") {
+			setting = strings.ReplaceAll(setting[1:len(setting)-1], "''", "'")
+		}
+		settings = append(settings, GUCSetting{
+			Name:    strings.ToLower(strings.TrimSpace(name)),
+			Setting: setting,
+			Source:  YSQLPgConfCSVSource,
+			Level:   GUCLevelServer,
+		})
+	}
+	return settings, nil
+}
+
+var memoryUnits = map[string]float64{
+	"B":  1,
+	"kB": 1024,
+	"MB": 1024 * 1024,
+	"GB": 1024 * 1024 * 1024,
+	"TB": 1024 * 1024 * 1024 * 1024,
+}
+
+var timeUnits = map[string]float64{
+	"us":  0.001,
+	"ms":  1,
+	"s":   1000,
+	"min": 60 * 1000,
+	"h":   60 * 60 * 1000,
+	"d":   24 * 60 * 60 * 1000,
+}
+
+// unitSize returns the size of a pg_settings unit such as "8kB" or "ms" and
+// the unit table it belongs to.
+func unitSize(unit string) (float64, map[string]float64) {
+	for _, table := range []map[string]float64{memoryUnits, timeUnits} {
+		for name, size := range table {
+			if unit == name {
+				return size, table
+			}
+			if strings.HasSuffix(unit, name) {
+				if n, err := strconv.ParseFloat(strings.TrimSuffix(unit, name), 64); err == nil {
+					return n * size, table
+				}
+			}
+		}
+	}
+	return 0, nil
+}
+
+// normalizeGUCValue converts a setting as written in a configuration file to
+// the form pg_settings.setting reports, so that both can be compared.
+func normalizeGUCValue(value, unit, vartype string) string {
+	value = strings.TrimSpace(value)
+	switch vartype {
+	case "bool":
+		switch strings.ToLower(value) {
+		case "on", "true", "yes", "1", "t", "y":
+			return "on"
+		case "off", "false", "no", "0", "f", "n":
+			return "off"
+		}
+	case "integer", "real":
+		number := value
+		factor := 1.0
+		if base, table := unitSize(unit); table != nil {
+			for name, size := range table {
+				if strings.HasSuffix(value, name) {
+					if _, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, name)), 64); err == nil {
+						number = strings.TrimSpace(strings.TrimSuffix(value, name))
+						factor = size / base
+						break
+					}
+				}
+			}
+		}
+		if n, err := strconv.ParseFloat(number, 64); err == nil {
+			n *= factor
+			if vartype == "integer" {
+				return strconv.FormatInt(int64(math.Round(n)), 10)
+			}
+			return strconv.FormatFloat(n, 'g', -1, 64)
+		}
+	case "enum":
+		return strings.ToLower(value)
+	}
+	return value
+}
@@ -0,0 +1,50 @@
+package dump
+
+import (
+	"testing"
+)
+
+func TestParseYSQLPgConfCSV(t *testing.T) {
+	settings, err := parseYSQLPgConfCSV(`work_mem=16MB,"log_line_prefix='%m [%p] '", Enable_HashJoin=off`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"work_mem":        "16MB",
+		"log_line_prefix": "%m [%p] ",
+		"enable_hashjoin": "off",
+	}
+	if len(settings) != len(expected) {
+		t.Fatalf("expected %d settings, got %v", len(expected), settings)
+	}
+	for _, s := range settings {
+		if expected[s.Name] != s.Setting {
+			t.Errorf("%s: expected %q, got %q", s.Name, expected[s.Name], s.Setting)
+		}
+		if s.Source != YSQLPgConfCSVSource || s.Level != GUCLevelServer {
+			t.Errorf("%s: unexpected provenance %s/%s", s.Name, s.Source, s.Level)
+		}
+	}
+}
+
+func TestNormalizeGUCValue(t *testing.T) {
+	tests := []struct {
+		value, unit, vartype, expected string
+	}{
+		{"16MB", "kB", "integer", "16384"},
+		{"1GB", "8kB", "integer", "131072"},
+		{"4096", "kB", "integer", "4096"},
+		{"1min", "ms", "integer", "60000"},
+		{"-1", "ms", "integer", "-1"},
+		{"1.10", "", "real", "1.1"},
+		{"true", "", "bool", "on"},
+		{"OFF", "", "bool", "off"},
+		{"Partition", "", "enum", "partition"},
+	}
+	for _, tt := range tests {
+		if got := normalizeGUCValue(tt.value, tt.unit, tt.vartype); got != tt.expected {
+			t.Errorf("normalizeGUCValue(%q, %q, %q) = %q, expected %q", tt.value, tt.unit, tt.vartype, got, tt.expected)
+		}
+	}
+}